@@ -0,0 +1,227 @@
+package metaphone3
+
+import "testing"
+
+// TestEncodingSuffixes checks the keys of words with productive english suffixes, which
+// cross the C, T, S, G, and X encoders
+func TestEncodingSuffixes(t *testing.T) {
+	table := []struct {
+		in, prim, sec string
+	}{
+		// "-TION" is "XN"
+		{"nation", "NXN", ""},
+		{"station", "STXN", ""},
+		{"action", "AKXN", ""},
+		{"motion", "MXN", ""},
+		{"relation", "RLXN", ""},
+		{"creation", "KRXN", ""},
+		{"attention", "ATNXN", ""},
+		{"position", "PSXN", ""},
+		{"addition", "ATXN", ""},
+		{"solution", "SLXN", ""},
+		{"emotion", "AMXN", ""},
+		{"question", "KSXN", ""},
+		{"digestion", "TJSXN", "TKSXN"},
+		{"suggestion", "SKJSXN", ""},
+		{"exhaustion", "AKSSXN", ""},
+		{"combustion", "KMPSXN", ""},
+		{"nutrition", "NTRXN", ""},
+		{"ambition", "AMPXN", ""},
+		{"fraction", "FRKXN", ""},
+		{"election", "ALKXN", ""},
+		// "-SION" is "XN", or "JN" after a vowel or R
+		{"vision", "FJN", ""},
+		{"decision", "TSJN", ""},
+		{"division", "TFJN", ""},
+		{"television", "TLFJN", ""},
+		{"version", "FRJN", ""},
+		{"mansion", "MNXN", ""},
+		{"tension", "TNXN", ""},
+		{"pension", "PNXN", ""},
+		{"expansion", "AKSPNXN", ""},
+		{"confusion", "KNFJN", ""},
+		{"explosion", "AKSPLJN", ""},
+		{"conclusion", "KNKLJN", ""},
+		{"fusion", "FJN", ""},
+		{"passion", "PXN", ""},
+		{"mission", "MXN", ""},
+		{"admission", "ATMXN", ""},
+		{"profession", "PRFXN", ""},
+		{"emulsion", "AMLXN", ""},
+		{"excursion", "AKSKRJN", ""},
+		{"aversion", "AFRJN", ""},
+		// "-CIOUS" is "XS" with an "SS" alternate
+		{"gracious", "KRXS", "KRSS"},
+		{"precious", "PRXS", "PRSS"},
+		{"delicious", "TLXS", "TLSS"},
+		{"spacious", "SPXS", "SPSS"},
+		{"vicious", "FXS", "FSS"},
+		{"conscious", "KNXS", ""},
+		{"suspicious", "SSPXS", "SSPSS"},
+		{"ferocious", "FRXS", "FRSS"},
+		{"atrocious", "ATRXS", "ATRSS"},
+		{"tenacious", "TNXS", "TNSS"},
+		{"audacious", "ATXS", "ATSS"},
+		{"malicious", "MLXS", "MLSS"},
+		{"judicious", "JTXS", "JTSS"},
+		{"capricious", "KPRXS", "KPRSS"},
+		{"voracious", "FRXS", "FRSS"},
+		{"precocious", "PRKXS", "PRKSS"},
+		{"luscious", "LXS", ""},
+		{"auspicious", "ASPXS", "ASPSS"},
+		{"loquacious", "LKXS", "LKSS"},
+		{"specious", "SPXS", "SPSS"},
+		// "-TIOUS" is "XS" with a "TS" alternate
+		{"cautious", "KXS", "KTS"},
+		{"ambitious", "AMPXS", "AMPTS"},
+		{"nutritious", "NTRXS", "NTRTS"},
+		{"fictitious", "FKTXS", "FKTTS"},
+		{"infectious", "ANFKXS", "ANFKTS"},
+		{"pretentious", "PRTNXS", "PRTNTS"},
+		{"conscientious", "KNXNXS", "KNXNTS"},
+		{"facetious", "FSXS", "FSTS"},
+		{"superstitious", "SPRSTXS", "SPRSTTS"},
+		{"contentious", "KNTNXS", "KNTNTS"},
+		{"flirtatious", "FLRTXS", "FLRTTS"},
+		{"repetitious", "RPTXS", "RPTTS"},
+		{"seditious", "STXS", "STTS"},
+		{"licentious", "LSNXS", "LSNTS"},
+		{"propitious", "PRPXS", "PRPTS"},
+		{"surreptitious", "SRPTXS", "SRPTTS"},
+		{"vexatious", "FKSXS", "FKSTS"},
+		{"rambunctious", "RMPNKXS", "RMPNKTS"},
+		{"expeditious", "AKSPTXS", "AKSPTTS"},
+		{"scrumptious", "SKRMPXS", "SKRMTS"},
+		// "-GEOUS" and "-GIOUS" are "JS", "-XIOUS" is "KXS"
+		{"gorgeous", "KRJS", ""},
+		{"courageous", "KRJS", ""},
+		{"outrageous", "ATRJS", ""},
+		{"advantageous", "ATFNTJS", ""},
+		{"religious", "RLJS", ""},
+		{"prodigious", "PRTJS", ""},
+		{"contagious", "KNTJS", ""},
+		{"egregious", "AKRJS", ""},
+		{"sacrilegious", "SKRLJS", ""},
+		{"litigious", "LTJS", ""},
+		{"prestigious", "PRSTJS", ""},
+		{"umbrageous", "AMPRJS", ""},
+		{"rampageous", "RMPJS", ""},
+		{"disadvantageous", "TSTFNTJS", ""},
+		{"noxious", "NKXS", "NKSS"},
+		{"anxious", "ANKXS", "ANKSS"},
+		{"obnoxious", "APNKXS", "APNKSS"},
+		{"tortious", "TRXS", "TRTS"},
+		// "-GION" and "-GIAN" are "JN" with a "KN" alternate
+		{"region", "RJN", "RKN"},
+		{"legion", "LJN", "LKN"},
+		{"religion", "RLJN", "RLKN"},
+		{"contagion", "KNTJN", "KNTKN"},
+		{"collegian", "KLJN", "KLKN"},
+		{"pidgin", "PJN", ""},
+		{"Georgian", "JRJN", "KRKN"},
+		{"Norwegian", "NRJN", "NRKN"},
+		{"regional", "RJNL", "RKNL"},
+		{"legionnaire", "LJNR", "LKNR"},
+		{"religions", "RLJNS", "RLKNS"},
+		{"regions", "RJNS", "RKNS"},
+		{"legions", "LJNS", "LKNS"},
+		{"irreligion", "ARLJN", "ARLKN"},
+		{"allegiance", "ALJNTS", "ALKNTS"},
+		{"Belgian", "PLJN", "PLKN"},
+		{"Glaswegian", "KLSJN", "KLSKN"},
+		{"theologian", "0LJN", ""},
+		{"astrologian", "ASTRLJN", ""},
+		{"collegians", "KLJNS", "KLKNS"},
+		// "-DGE" is a single "J"
+		{"bridge", "PRJ", ""},
+		{"judge", "JJ", ""},
+		{"edge", "AJ", ""},
+		{"hedge", "HJ", ""},
+		{"ledge", "LJ", ""},
+		{"ridge", "RJ", ""},
+		{"fudge", "FJ", ""},
+		{"nudge", "NJ", ""},
+		{"badge", "PJ", ""},
+		{"lodge", "LJ", ""},
+		{"dodge", "TJ", ""},
+		{"wedge", "AJ", ""},
+		{"knowledge", "NLJ", ""},
+		{"cartridge", "KRTRJ", ""},
+		{"partridge", "PRTRJ", ""},
+		{"porridge", "PRJ", ""},
+		{"acknowledge", "AKNLJ", ""},
+		{"abridge", "APRJ", ""},
+		{"begrudge", "PKRJ", ""},
+		{"sledge", "SLJ", "XLJ"},
+		// "-TURE" is "XR" with a "TR" alternate
+		{"nature", "NXR", "NTR"},
+		{"picture", "PKXR", "PKTR"},
+		{"future", "FXR", "FTR"},
+		{"culture", "KLXR", "KLTR"},
+		{"mixture", "MKSXR", "MKSTR"},
+		{"texture", "TKSXR", "TKSTR"},
+		{"creature", "KRXR", "KRTR"},
+		{"feature", "FXR", "FTR"},
+		{"capture", "KPXR", "KPTR"},
+		{"lecture", "LKXR", "LKTR"},
+		{"furniture", "FRNXR", "FRNTR"},
+		{"adventure", "ATFNXR", "ATFNTR"},
+		{"signature", "SKNXR", "SKNTR"},
+		{"temperature", "TMPRXR", "TMPRTR"},
+		{"literature", "LTRXR", "LTRTR"},
+		{"structure", "STRKXR", "STRKTR"},
+		{"moisture", "MSXR", "MSTR"},
+		{"posture", "PSXR", "PSTR"},
+		{"gesture", "JSXR", "KSTR"},
+		{"venture", "FNXR", "FNTR"},
+		// "-SURE" is "JR" after a vowel, otherwise "XR"
+		{"measure", "MJR", ""},
+		{"pleasure", "PLJR", ""},
+		{"treasure", "TRJR", ""},
+		{"leisure", "LJR", ""},
+		{"closure", "KLJR", ""},
+		{"exposure", "AKSPJR", ""},
+		{"composure", "KMPJR", ""},
+		{"enclosure", "ANKLJR", ""},
+		{"disclosure", "TSKLJR", ""},
+		{"sure", "XR", ""},
+		{"assure", "AXR", ""},
+		{"insure", "ANXR", ""},
+		{"ensure", "ANXR", ""},
+		{"pressure", "PRXR", ""},
+		{"erasure", "ARJR", ""},
+		{"seizure", "SJR", "SSR"},
+		{"censure", "SNXR", ""},
+		{"tonsure", "TNXR", ""},
+		{"fissure", "FXR", ""},
+		{"leasure", "LJR", ""},
+		// "-CIALLY" is "XL" with an "SL" alternate, "-TIALLY" has a "TL" alternate
+		{"especially", "ASPXL", "ASPSL"},
+		{"socially", "SXL", "SSL"},
+		{"officially", "AFXL", "AFSL"},
+		{"financially", "FNNXL", "FNNSL"},
+		{"commercially", "KMRXL", "KMRSL"},
+		{"artificially", "ARTFXL", "ARTFSL"},
+		{"crucially", "KRXL", "KRSL"},
+		{"specially", "SPXL", "SPSL"},
+		{"beneficially", "PNFXL", "PNFSL"},
+		{"provincially", "PRFNXL", "PRFNSL"},
+		{"racially", "RXL", "RSL"},
+		{"facially", "FXL", "FSL"},
+		{"glacially", "KLXL", "KLSL"},
+		{"judicially", "JTXL", "JTSL"},
+		{"superficially", "SPRFXL", "SPRFSL"},
+		{"sacrificially", "SKRFXL", "SKRFSL"},
+		{"prejudicially", "PRJTXL", "PRJTSL"},
+		{"initially", "ANXL", "ANTL"},
+		{"partially", "PRXL", "PRTL"},
+		{"essentially", "ASNXL", "ASNTL"},
+	}
+
+	e := &Encoder{}
+	for _, test := range table {
+		if prim, sec := e.Encode(test.in); prim != test.prim || sec != test.sec {
+			t.Errorf("Expected '%v' to be %v %v, got %v %v", test.in, test.prim, test.sec, prim, sec)
+		}
+	}
+}
@@ -1,8 +1,11 @@
 package metaphone3
 
 import (
+	"encoding/csv"
 	"fmt"
+	"strings"
 	"unicode"
+	"unicode/utf8"
 )
 
 // debug flag to output additional data during encoding
@@ -11,6 +14,13 @@ var debug = false
 // DefaultMaxLength is the max number of runes in a result when not specified in the encoder
 var DefaultMaxLength = 8
 
+// DefaultPadChar is the rune used to pad metaphones shorter than MinLength when the
+// encoder's PadChar isn't set
+var DefaultPadChar = ' '
+
+// the largest key the java implementation allows (MAX_KEY_ALLOCATION)
+const javaMaxKeyLength = 32
+
 // Encoder is a metaphone3 encoder that contains options and state for encoding.  It is not
 // safe to use across goroutines.
 type Encoder struct {
@@ -30,6 +40,80 @@ type Encoder struct {
 	// The max allowed length of the output metaphs, if <= 0 then the DefaultMaxLength is used
 	MaxLength int
 
+	// MinLength right-pads metaphones shorter than it with PadChar, e.g. "A" => "A000"
+	// with a MinLength of 4 and a PadChar of '0'. Metaphones are never padded past
+	// MaxLength, and blank metaphones are not padded. If <= 0 there is no padding.
+	MinLength int
+
+	// PadChar is the rune used to pad metaphones to MinLength, if 0 then the
+	// DefaultPadChar is used
+	PadChar rune
+
+	// JavaCompatTrim replicates the reference java implementation's key length handling,
+	// where the main loop runs until both keys are past MaxLength and MaxLength is capped
+	// at 32. The keys are still trimmed to MaxLength, so this only changes the output
+	// when MaxLength is more than 32.
+	JavaCompatTrim bool
+
+	// StripNameSuffixes removes trailing name suffixes before encoding so that
+	// "John Smith III" encodes like "John Smith". The suffixes are JR, SR,
+	// II through X, PHD, MD, DDS, and ESQ with or without periods, e.g. "Jr.".
+	// A suffix is only removed when it follows another word.
+	StripNameSuffixes bool
+
+	// LowercaseOutput emits the metaphones in lowercase, e.g. "sm0" instead of "SM0".
+	// The '0' used for "TH" is unchanged.
+	LowercaseOutput bool
+
+	// AmericanFlap merges 'T' and 'D' between vowels, since americans pronounce both
+	// as the same "flap" sound in words like "latter" and "ladder" or "metal" and "medal".
+	// This only changes the output when EncodeExact is true, otherwise 'T' and 'D' are
+	// already encoded the same. This is an american-specific fuzziness option.
+	AmericanFlap bool
+
+	// PreserveVowelRuns keeps every encoded vowel as a separate 'A' instead of
+	// collapsing consecutive 'A's, so the number of vowel nuclei can be counted
+	// from the metaphone. This only changes the output when EncodeVowels is true.
+	// Adjacent vowels in the input (e.g. the "OI" in "noisy") are still skipped
+	// together and encoded as one 'A', so this mainly keeps 'A's that meet across
+	// a silent consonant, e.g. "higher" is "HAAR" instead of "HAR".  The exception
+	// is "EA" that is two syllables, e.g. "create" and "creyate" are "KRAAT" but
+	// "great" is "KRAT".
+	PreserveVowelRuns bool
+
+	// SpellAcronyms encodes inputs that look like acronyms as their spelled out
+	// letters, e.g. "FBI" is encoded like "EF BEE EYE".  An input looks like an
+	// acronym if it is 2 to 5 capital letters with no vowels (e.g. "HTML"), or is
+	// one of a list of common acronyms with vowels (e.g. "IBM", "CIA", "USA").
+	// This is only a heuristic, so all capital words without vowels like "MR" are
+	// also spelled out.
+	SpellAcronyms bool
+
+	// PronounceInitialH encodes the initial 'H' in words where americans usually
+	// drop it, e.g. "herb", "hour", "honest", and "heir", as it would be pronounced.
+	// By default "herb" has an 'H' primary (british and the name) with an alternate
+	// without it (the american plant), and the others have no 'H'.
+	PronounceInitialH bool
+
+	// SpellSymbols encodes the symbols '&', '@', '%', and '+' as the words "AND",
+	// "AT", "PERCENT", and "PLUS", so e.g. "R&B" is encoded like "R AND B" instead
+	// of the '&' being dropped.
+	SpellSymbols bool
+
+	// DropInitialVowel drops the 'A' that an initial vowel sound is encoded as, so
+	// with EncodeVowels false the metaphone is only the consonants, e.g. "Omar" is
+	// "MR" instead of "AMR".  This includes vowels after a silent letter, e.g. "hour"
+	// is "R", so a word of only vowel sounds, e.g. "eye", is blank.  An 'H' after
+	// the dropped vowel is kept, e.g. "Ahmed" is "HMT".
+	DropInitialVowel bool
+
+	// CollapseRepeats shortens an ending made of a run of 2 to 4 letters repeated
+	// at least twice down to one repeat, e.g. "hahaha" like "ha" and "lololol" like
+	// "lol", so that interjections typed in informal text get the same metaphone
+	// however long they are.  Words with a repeated ending are shortened too,
+	// e.g. "banana" is encoded like "bana".
+	CollapseRepeats bool
+
 	in                 []rune
 	idx                int
 	lastIdx            int
@@ -48,18 +132,43 @@ func (e *Encoder) Encode(in string) (primary, secondary string) {
 	if e.MaxLength <= 0 {
 		e.MaxLength = DefaultMaxLength
 	}
+	maxLen := e.MaxLength
+	if e.JavaCompatTrim && maxLen > javaMaxKeyLength {
+		maxLen = javaMaxKeyLength
+	}
 
 	e.flagAlInversion = false
 
+	if e.SpellAcronyms && isAcronym(in) {
+		in = spellLetters(in)
+	}
+	if e.SpellSymbols {
+		in = spellSymbols(in)
+	}
+
 	// setup our input buffer and to-upper everything
 	e.in = make([]rune, 0, len(in))
 	for _, r := range in {
-		e.in = append(e.in, unicode.ToUpper(r))
+		r = unicode.ToUpper(r)
+		if r == 'Œ' {
+			// the ligature is encoded like the spelled out "OE", e.g. "œuvre" like "oeuvre"
+			e.in = append(e.in, 'O', 'E')
+			continue
+		}
+		e.in = append(e.in, r)
+	}
+	e.in = stripClitics(e.in)
+	if e.CollapseRepeats {
+		e.in = collapseReduplication(e.in)
+	}
+	e.in = collapseDrawnOut(e.in)
+	if e.StripNameSuffixes {
+		e.in = stripNameSuffixes(e.in)
 	}
 	e.lastIdx = len(e.in) - 1
 
-	e.primBuf = primeBuf(e.primBuf, e.MaxLength)
-	e.secondBuf = primeBuf(e.secondBuf, e.MaxLength)
+	e.primBuf = primeBuf(e.primBuf, maxLen)
+	e.secondBuf = primeBuf(e.secondBuf, maxLen)
 
 	// lets go rune-by-rune through the input string
 	for e.idx = 0; e.idx < len(e.in); e.idx++ {
@@ -67,8 +176,12 @@ func (e *Encoder) Encode(in string) (primary, secondary string) {
 		// double check our output buffers, if they're full then we're done
 		// we're not checking exact "=" just be compat with the reference java implementation
 		// that means our buffers could be longer than MaxLength by a bit
-		if len(e.primBuf) >= e.MaxLength && len(e.secondBuf) >= e.MaxLength {
-			break
+		if len(e.primBuf) >= maxLen && len(e.secondBuf) >= maxLen {
+			// the java implementation keeps going until both are past full,
+			// the extra is trimmed below
+			if !e.JavaCompatTrim || (len(e.primBuf) > maxLen && len(e.secondBuf) > maxLen) {
+				break
+			}
 		}
 
 		if debug {
@@ -135,12 +248,32 @@ func (e *Encoder) Encode(in string) (primary, secondary string) {
 		}
 	}
 
+	e.encodeInterjection()
+
 	// trim our buffers if needed
-	if len(e.primBuf) > e.MaxLength {
-		e.primBuf = e.primBuf[:e.MaxLength]
+	if len(e.primBuf) > maxLen {
+		e.primBuf = e.primBuf[:maxLen]
 	}
-	if len(e.secondBuf) > e.MaxLength {
-		e.secondBuf = e.secondBuf[:e.MaxLength]
+	if len(e.secondBuf) > maxLen {
+		e.secondBuf = e.secondBuf[:maxLen]
+	}
+
+	if e.LowercaseOutput {
+		toLower(e.primBuf)
+		toLower(e.secondBuf)
+	}
+
+	if e.MinLength > 0 {
+		minLen := e.MinLength
+		if minLen > maxLen {
+			minLen = maxLen
+		}
+		padChar := e.PadChar
+		if padChar == 0 {
+			padChar = DefaultPadChar
+		}
+		e.primBuf = pad(e.primBuf, minLen, padChar)
+		e.secondBuf = pad(e.secondBuf, minLen, padChar)
 	}
 
 	if areEqual(e.primBuf, e.secondBuf) {
@@ -150,17 +283,210 @@ func (e *Encoder) Encode(in string) (primary, secondary string) {
 	return string(e.primBuf), string(e.secondBuf)
 }
 
+// EncodeReversed returns the primary and secondary metaphones of the input spelled
+// backwards, e.g. "Smith" is encoded as "htimS".  This isn't how the word sounds,
+// but it's useful as a secondary blocking key to catch transpositions at the start
+// of a word.  The input is reversed by rune, so it's safe for any unicode input.
+func (e *Encoder) EncodeReversed(in string) (primary, secondary string) {
+	runes := []rune(in)
+	for i, j := 0, len(runes)-1; i < j; i, j = i+1, j-1 {
+		runes[i], runes[j] = runes[j], runes[i]
+	}
+	return e.Encode(string(runes))
+}
+
+// Result is the metaphones of a single word of a phrase, see EncodePhrase.
+type Result struct {
+	Word      string
+	Primary   string
+	Secondary string
+}
+
+// EncodePhrase splits the input on whitespace and encodes each word separately,
+// e.g. "San Francisco" is encoded as "San" and "Francisco" rather than run
+// together as one word.  The results are in the same order as the words, and
+// an input with no words returns nil.  With StripNameSuffixes the suffixes are
+// removed from the whole phrase first, e.g. "John Smith Jr" is "John" and "Smith".
+func (e *Encoder) EncodePhrase(in string) []Result {
+	if e.StripNameSuffixes {
+		runes := []rune(in)
+		upper := make([]rune, len(runes))
+		for i, r := range runes {
+			upper[i] = unicode.ToUpper(r)
+		}
+		in = string(runes[:len(stripNameSuffixes(upper))])
+	}
+
+	words := strings.Fields(in)
+	if len(words) == 0 {
+		return nil
+	}
+
+	results := make([]Result, len(words))
+	for i, w := range words {
+		prim, second := e.Encode(w)
+		results[i] = Result{Word: w, Primary: prim, Secondary: second}
+	}
+	return results
+}
+
+// EncodeWithLen returns the primary and secondary metaphones along with the rune
+// length of the original input.  With a short MaxLength e.g. "Lee" and "Leonardo"
+// share a metaphone but have very different lengths, so this lets callers add a
+// cheap length guard to matches without measuring the input again.
+func (e *Encoder) EncodeWithLen(in string) (primary, secondary string, runeLen int) {
+	primary, secondary = e.Encode(in)
+	return primary, secondary, utf8.RuneCountInString(in)
+}
+
+// SameSound encodes both inputs with the encoder's options and returns true if
+// they share a metaphone.  Any pairing counts as a match, so a's primary matching
+// b's secondary (or vice-versa) is considered the same sound.
+func (e *Encoder) SameSound(a, b string) bool {
+	aPrim, aSecond := e.Encode(a)
+	bPrim, bSecond := e.Encode(b)
+
+	if aPrim == "" || bPrim == "" {
+		return false
+	}
+
+	return aPrim == bPrim ||
+		(bSecond != "" && aPrim == bSecond) ||
+		(aSecond != "" && (aSecond == bPrim || aSecond == bSecond))
+}
+
+// MatchesAny encodes the input and returns true if either its primary or
+// secondary metaphone is in keys, e.g. a set of metaphones for candidate names.
+// Like SameSound either pronunciation counts as a match, and a blank metaphone
+// never matches.  The keys must have been encoded with the same options.
+func (e *Encoder) MatchesAny(in string, keys map[string]struct{}) bool {
+	prim, second := e.Encode(in)
+	if prim == "" {
+		return false
+	}
+
+	if _, ok := keys[prim]; ok {
+		return true
+	}
+	if second != "" {
+		if _, ok := keys[second]; ok {
+			return true
+		}
+	}
+	return false
+}
+
+// EqualKeys returns true if the two metaphones are the same ignoring case, so
+// keys encoded with and without LowercaseOutput can be compared.  Like the
+// metaphones themselves, keys from encoders with other differing options are
+// not comparable.
+func EqualKeys(a, b string) bool {
+	return strings.EqualFold(a, b)
+}
+
+// OutputAlphabet is every character that can appear in an uppercase metaphone.
+// "0" is used for "TH".
+const OutputAlphabet = "0ABDFGHJKLMNPRSTVX"
+
+// ValidSymbol returns true if r can appear in a metaphone, in either case.
+// 'B', 'D', 'G', 'V' are only emitted when EncodeExact is true.
+func ValidSymbol(r rune) bool {
+	return strings.ContainsRune(OutputAlphabet, unicode.ToUpper(r))
+}
+
+// IsLikelyKey returns true if s looks like it's already a metaphone, so pipelines
+// can avoid encoding a key twice.  This is a heuristic: s must be all uppercase or
+// all lowercase and only use characters in the OutputAlphabet.  Words of any length
+// without the vowels E, I, O, U, or Y (e.g. "BRANDT") will also look like keys.
+func IsLikelyKey(s string) bool {
+	if s == "" {
+		return false
+	}
+	if s != strings.ToUpper(s) && s != strings.ToLower(s) {
+		return false
+	}
+	for _, r := range s {
+		if !ValidSymbol(r) {
+			return false
+		}
+	}
+	return true
+}
+
+// EncodeHash encodes the input and returns a 64-bit FNV-1a hash of the primary
+// and secondary metaphones.  A blank metaphone hashes to 0.  The hash is stable
+// across runs and versions so hashes can be persisted, but like the metaphones
+// themselves they are only comparable when produced with the same options.
+func (e *Encoder) EncodeHash(in string) (primary, secondary uint64) {
+	prim, second := e.Encode(in)
+	return hashKey(prim), hashKey(second)
+}
+
+// hashKey returns the 64-bit FNV-1a hash of a metaphone, or 0 if it's blank.
+// Changing this is a breaking change for anyone persisting hashes.
+func hashKey(key string) uint64 {
+	if key == "" {
+		return 0
+	}
+
+	const (
+		offset64 = 14695981039346656037
+		prime64  = 1099511628211
+	)
+
+	h := uint64(offset64)
+	for i := 0; i < len(key); i++ {
+		h ^= uint64(key[i])
+		h *= prime64
+	}
+	return h
+}
+
+// GenerateGolden returns the lines of a testdata ".test" file for the words, so
+// new test words can be generated and then reviewed instead of hand-written.
+// Each line is the word followed by the primary and secondary metaphones of the
+// default, EncodeVowels and EncodeExact, EncodeExact, and EncodeVowels encoders.
+func GenerateGolden(words []string) string {
+	encs := []*Encoder{
+		{},
+		{EncodeVowels: true, EncodeExact: true},
+		{EncodeExact: true},
+		{EncodeVowels: true},
+	}
+
+	sb := &strings.Builder{}
+	w := csv.NewWriter(sb)
+	for _, word := range words {
+		line := []string{word}
+		for _, e := range encs {
+			prim, second := e.Encode(word)
+			line = append(line, prim, second)
+		}
+		w.Write(line)
+	}
+	w.Flush()
+
+	return sb.String()
+}
+
 //////////////////////////////////////////////////////////////////////////////////////////////////////
 // Detailed encoder functions
 //////////////////////////////////////////////////////////////////////////////////////////////////////
 
+// Encodes a lone 'H', e.g. "h", "hh", as 'H' so that it isn't blank
+func (e *Encoder) encodeInterjection() {
+	if len(e.primBuf) == 0 && len(e.secondBuf) == 0 && e.stringExact("H", "HH") {
+		e.metaphAdd('H')
+	}
+}
+
 func (e *Encoder) encodeB() {
 	if e.encodeSilentB() {
 		return
 	}
 
-	// "-mb", e.g", "dumb", already skipped over under
-	// 'M', altho it should really be handled here...
+	// silent "-mb", e.g. "dumb", is already skipped over
+	// under 'M', see encodeMb
 	e.metaphAddExactApprox("B", "P")
 
 	// skip double B, or BPx where X isn't H
@@ -258,10 +584,13 @@ func (e *Encoder) encodeCh() bool {
 	}
 
 	if e.encodeChae() ||
+		e.encodeBritishWich() ||
 		e.encodeChToH() ||
 		e.encodeSilentCh() ||
+		e.encodeScottishCh() ||
 		e.encodeArch() ||
 		e.encodeChToX() ||
+		e.encodeFrenchChToX() ||
 		e.encodeEnglishChToK() ||
 		e.encodeGermanicChToK() ||
 		e.encodeGreekChInitial() ||
@@ -300,6 +629,32 @@ func (e *Encoder) encodeChae() bool {
 	return false
 }
 
+// Encodes english "-WICH" endings. Well known british place names
+// reduce it to 'idge', e.g. 'norwich' => 'norridge'. The 'W' is already silent
+// since it's treated as a vowel, and americans will often say 'itch' so
+// 'X' is given as the alternate. Otherwise it's english 'itch', e.g. 'ipswich',
+// and never a germanic 'K'. "-WICK" needs nothing special here
+// since e.g. 'warwick' => 'worrick' already
+func (e *Encoder) encodeBritishWich() bool {
+	if !e.stringAt(-2, "WICH") {
+		return false
+	}
+
+	if e.stringStart("HARWICH", "NORWICH", "DULWICH", "WOOLWICH", "GREENWICH") {
+		e.metaphAddAlt('J', 'X')
+		e.idx++
+		return true
+	}
+
+	if e.stringAtEnd(-2, "WICH", "WICHES") {
+		e.metaphAdd('X')
+		e.idx++
+		return true
+	}
+
+	return false
+}
+
 // Encodes transliterations from the hebrew where the
 // sound 'kh' is represented as "-CH-". The normal pronounciation
 // of this in english is either 'h' or 'kh', and alternate
@@ -322,7 +677,7 @@ func (e *Encoder) encodeChToH() bool {
 
 func (e *Encoder) encodeSilentCh() bool {
 	if e.stringAt(-2, "YACHT", "FUCHSIA") ||
-		e.stringStart("STRACHAN", "CRICHTON") ||
+		e.stringStart("STRACHAN", "CRICHTON", "BUCCLEUCH") ||
 		(e.stringAt(-3, "DRACHM") && !e.stringAt(-3, "DRACHMA")) {
 		e.idx++
 		return true
@@ -331,6 +686,24 @@ func (e *Encoder) encodeSilentCh() bool {
 	return false
 }
 
+// Encodes scottish and gaelic "-CH-" pronounced like the german 'ch'. Americans
+// usually say 'K', so it's encoded as 'K' with an 'H' alternate for the scots
+// "kh", e.g. "lachlan", "auchinleck"
+func (e *Encoder) encodeScottishCh() bool {
+	if e.stringAt(-2, "LACHLAN") ||
+		e.stringStart("ACHNA", "OCHIL", "AUCHIN", "BRECHIN", "AUCHTER") ||
+		// e.g. "loch", "mcculloch", "kinloch", "gairloch", "murdoch", "riddoch"
+		e.stringExact("LOCH", "LOCHS") ||
+		e.stringAtEnd(-3, "LLOCH", "NLOCH", "RLOCH", "RDOCH", "DDOCH") {
+
+		e.metaphAddAlt('K', 'H')
+		e.idx++
+		return true
+	}
+
+	return false
+}
+
 func (e *Encoder) encodeChToX() bool {
 	// e.g. 'approach', 'beach'
 	if (e.stringAt(-2, "OACH", "EACH", "EECH", "OUCH", "OOCH", "MUCH", "SUCH") && !e.stringAt(-3, "JOACH")) ||
@@ -351,6 +724,33 @@ func (e *Encoder) encodeChToX() bool {
 	return false
 }
 
+// french loans where 'CH' is always 'X', like initial 'chef' and 'chute'
+// but unlike greek 'K' e.g. 'chorus' and english 'X' with a 'K' alternate
+func (e *Encoder) encodeFrenchChToX() bool {
+	// e.g. 'niche', 'fiche', 'microfiche' but not italian 'tecniche', 'grafiche'
+	if (e.stringAtEnd(-2, "NICHE", "FICHE", "NICHES", "FICHES") &&
+		!e.stringAt(-3, "ANICHE", "CNICHE", "ONICHE", "AFICHE", "IFICHE")) ||
+		e.stringAtEnd(-2, "SACHET", "SACHETS") ||
+		// e.g. 'crochet', 'ricochet', 'brochure' but not greek 'spirochete' or hebrew 'schochet'
+		(e.stringAt(-2, "COCHET", "ROCHET") && !e.stringAt(-5, "SPIROCHET")) ||
+		e.stringAt(-1, "OCHURE", "ECHELON") ||
+		// e.g. 'cache', 'caches', 'cached' but not 'cachexia' or 'cachero'
+		(e.idx > 1 && rootOrInflections(e.in[e.idx-2:], "CACHE")) ||
+		e.stringAt(-2, "MACHINE", "MACHINI") ||
+		e.stringAt(-3, "QUICHE", "CLICHE", "NONCHALAN") ||
+		e.stringAt(-4, "PANACHE", "USTACHE") ||
+		e.stringAt(-5, "PASTICHE") ||
+		// but not italian 'michele', which keeps its 'K' alternate
+		e.stringExact("MICHELL", "MICHELLE") {
+
+		e.metaphAdd('X')
+		e.idx++
+		return true
+	}
+
+	return false
+}
+
 func (e *Encoder) encodeEnglishChToK() bool {
 	//'ache', 'echo', alternate spelling of 'michael'
 	if (e.idx == 1 && rootOrInflections(e.in, "ACHE")) ||
@@ -542,7 +942,9 @@ func (e *Encoder) encodeCc() bool {
 		}
 
 		//'bacci', 'bertucci', other italian
+		//final italian 'bocce', 'lecce' but not british 'recce'
 		if e.stringAtEnd(2, "I") ||
+			(e.stringAtEnd(2, "E") && !e.stringAt(-2, "RECCE")) ||
 			e.stringAt(2, "IO") || e.stringAtEnd(2, "INO", "INI") {
 			e.metaphAdd('X')
 			e.advanceCounter(2, 1)
@@ -550,8 +952,8 @@ func (e *Encoder) encodeCc() bool {
 		}
 
 		//'accident', 'accede' 'succeed'
-		if e.stringAt(2, "I", "E", "Y") && //except 'bellocchio','bacchus', 'soccer' get K
-			!(e.charAt(2, 'H') || e.stringAt(-2, "SOCCER")) {
+		if e.stringAt(2, "I", "E", "Y") && //except 'bellocchio','bacchus', 'soccer', 'recce' get K
+			!(e.charAt(2, 'H') || e.stringAt(-2, "RECCE", "SOCCER")) {
 			e.metaphAddStr("KS", "KS")
 			e.advanceCounter(2, 1)
 			return true
@@ -734,8 +1136,8 @@ func (e *Encoder) encodeCs() bool {
 }
 
 func (e *Encoder) encodeD() {
-	if e.encodeDg() || e.encodeDj() || e.encodeDtDd() ||
-		e.encodeDToJ() || e.encodeDous() || e.encodeSilentD() {
+	if e.encodeDg() || e.encodeDj() || e.encodeDToJ() || e.encodeDous() ||
+		e.encodeSilentD() || e.encodeAmericanFlap() || e.encodeDtDd() {
 		return
 	}
 
@@ -813,6 +1215,28 @@ func (e *Encoder) encodeDtDd() bool {
 	return false
 }
 
+// Encodes "-T-", "-D-", "-TT-", and "-DD-" between vowels as the
+// american "flap" when AmericanFlap is set, e.g. "latter", "ladder"
+func (e *Encoder) encodeAmericanFlap() bool {
+	if !e.AmericanFlap || !e.isVowelAt(-1) {
+		return false
+	}
+
+	next := 1
+	if e.charAt(1, e.in[e.idx]) {
+		next = 2
+	}
+
+	// final silent 'E' doesn't make a flap, e.g. "bate", "bates"
+	if !e.isVowelAt(next) || e.stringAtEnd(next, "E", "ES") {
+		return false
+	}
+
+	e.metaphAddExactApprox("D", "T")
+	e.idx += next - 1
+	return true
+}
+
 func (e *Encoder) encodeDToJ() bool {
 	// e.g. "module", "adulate"
 	if (e.stringAt(0, "DUL") && e.isVowelAt(-1) && e.isVowelAt(3)) ||
@@ -872,7 +1296,15 @@ func (e *Encoder) encodeG() {
 		return
 	}
 
-	if !e.stringAt(-1, "C", "K", "G", "Q") {
+	if e.stringAt(-3, "LENGTH", "RENGTH") {
+		// usually not pronounced, e.g. "strength", "length",
+		// with the spelled 'G' as the alternate
+		if e.EncodeExact {
+			e.metaphAddAlt(unicode.ReplacementChar, 'G')
+		} else {
+			e.metaphAddAlt(unicode.ReplacementChar, 'K')
+		}
+	} else if !e.stringAt(-1, "C", "K", "G", "Q") {
 		e.metaphAddExactApprox("G", "K")
 	}
 }
@@ -888,7 +1320,7 @@ func (e *Encoder) encodeGg() bool {
 			// 'ruggiero' but not 'snuggies'
 			(e.stringAt(-1, "UGGIE") && !(e.idx+3 == e.lastIdx || e.idx+4 == e.lastIdx)) ||
 			e.stringAtEnd(-1, "AGGI", "OGGI") ||
-			e.stringAt(-2, "SUGGES", "XAGGER", "REGGIE") {
+			e.stringAt(-2, "SUGGES", "XAGGER", "REGGIE", "VEGGIE") {
 
 			// expection where "-GG-" => KJ
 			if e.stringAt(-2, "SUGGEST") {
@@ -919,7 +1351,7 @@ func (e *Encoder) encodeGk() bool {
 
 func (e *Encoder) encodeGh() bool {
 	if e.charNextIs('H') {
-		if e.encodeGhAfterConsonant() || e.encodeInitialGh() || e.encodeGhToJ() || e.encodeGhToH() ||
+		if e.encodeBurgh() || e.encodeGhAfterConsonant() || e.encodeInitialGh() || e.encodeOughTable() || e.encodeGhToJ() || e.encodeGhToH() ||
 			e.encodeUght() || e.encodeGhHPartOfOtherWord() || e.encodeSilentGh() || e.encodeGhToF() {
 			return true
 		}
@@ -931,6 +1363,21 @@ func (e *Encoder) encodeGh() bool {
 	return false
 }
 
+// british "-BURGH" e.g. 'edinburgh' ends in a schwa like 'borough', but keep
+// the american 'G' e.g. 'pittsburgh' as the primary
+func (e *Encoder) encodeBurgh() bool {
+	if e.stringAtEnd(-3, "BURGH") {
+		if e.EncodeVowels {
+			e.metaphAddExactApproxAlt("G", "A", "K", "A")
+		} else {
+			e.metaphAddExactApproxAlt("G", "", "K", "")
+		}
+		e.idx++
+		return true
+	}
+	return false
+}
+
 func (e *Encoder) encodeGhAfterConsonant() bool {
 	// e.g. 'burgher', 'bingham'
 	if e.idx > 0 && !e.isVowelAt(-1) &&
@@ -945,9 +1392,11 @@ func (e *Encoder) encodeGhAfterConsonant() bool {
 
 func (e *Encoder) encodeInitialGh() bool {
 	if e.idx == 0 {
-		// e.g. "ghislane", "ghiradelli"
+		// italian "GHI-" is a hard 'G' e.g. "ghiradelli", "ghiberti",
+		// but give 'J' as an alternate since americans often soften
+		// it, e.g. "ghislane"
 		if e.charAt(2, 'I') {
-			e.metaphAdd('J')
+			e.metaphAddExactApproxAlt("G", "J", "K", "J")
 		} else {
 			e.metaphAddExactApprox("G", "K")
 		}
@@ -967,6 +1416,62 @@ func (e *Encoder) encodeGhToJ() bool {
 	return false
 }
 
+// the "-OUGH" word families and how their "GH" is pronounced, a blank
+// gh is silent. Most families only match at the start of the word, but
+// anywhere families also match in compounds e.g. "breakthrough", "although"
+var oughWords = []struct {
+	word, gh, alt string
+	anywhere      bool
+}{
+	// silent
+	{"BOUGH", "", "", false},
+	{"DOUGH", "", "", false},
+	{"HOUGH", "", "F", false},
+	{"BROUGH", "", "", true},
+	{"PLOUGH", "", "", false},
+	{"SLOUGH", "", "F", false},
+	{"THOUGH", "", "", true},
+	{"BOROUGH", "", "", true},
+	{"THROUGH", "", "", true},
+	{"THOROUGH", "", "", false},
+	// 'F'
+	{"COUGH", "F", "F", false},
+	{"ROUGH", "F", "F", false},
+	{"SOUGH", "F", "F", false},
+	{"TOUGH", "F", "F", false},
+	{"CHOUGH", "F", "F", false},
+	{"CLOUGH", "F", "F", false},
+	{"ENOUGH", "F", "F", false},
+	{"TROUGH", "F", "F", false},
+	// 'hiccough' == 'hiccup'
+	{"HICCOUGH", "P", "P", false},
+	// scots 'lough' == 'loch'
+	{"LOUGH", "K", "K", false},
+}
+
+func (e *Encoder) encodeOughTable() bool {
+	// "-OUGHT" e.g. "thought", "bought" is handled with "-UGHT"
+	if !e.stringAt(-2, "OUGH") || e.charAt(2, 'T') || e.stringAt(-3, "COUGHLAN") {
+		return false
+	}
+
+	for _, ough := range oughWords {
+		offset := 2 - len(ough.word)
+		if e.stringAt(offset, ough.word) && (ough.anywhere || e.idx+offset == 0) {
+			// the 'G' isn't silent in names like "doughan", "dougharty"
+			if ough.gh == "" && (e.stringAt(2, "A", "O", "U") || e.stringAtEnd(2, "I")) {
+				return false
+			}
+
+			e.metaphAddStr(ough.gh, ough.alt)
+			e.idx++
+			return true
+		}
+	}
+
+	return false
+}
+
 func (e *Encoder) encodeGhToH() bool {
 	// special cases
 	// e.g., 'donoghue', 'donaghy'
@@ -1040,15 +1545,7 @@ func (e *Encoder) encodeSilentGh() bool {
 func (e *Encoder) encodeGhSpecialCases() bool {
 	handled := false
 
-	// special case: 'hiccough' == 'hiccup'
-	if e.stringAt(-6, "HICCOUGH") {
-		e.metaphAdd('P')
-		handled = true
-	} else if e.stringStart("LOUGH") {
-		// special case: 'lough' alt spelling for scots 'loch'
-		e.metaphAdd('K')
-		handled = true
-	} else if e.stringStart("BALOGH") {
+	if e.stringStart("BALOGH") {
 		// hungarian
 		e.metaphAddExactApproxAlt("G", "", "K", "")
 		handled = true
@@ -1113,7 +1610,7 @@ func (e *Encoder) encodeGn() bool {
 				// Exceptions: following are cases where 'G' is pronounced
 				// in "assign" 'g' is silent, but not in "assignation"
 				!(e.stringAt(2, "ATE", "ITY", "ATOR", "ATION") ||
-					(e.stringAt(2, "AN", "AC", "IA", "UM") && !(e.stringAt(-3, "POIGNANT") || e.stringAt(-2, "COGNAC"))) ||
+					(e.stringAt(2, "AN", "AC", "IA", "UM") && !(e.stringAt(-3, "POIGNAN") || e.stringAt(-2, "COGNAC"))) ||
 					e.stringStart("SPIGNER", "STEGNER") ||
 					e.stringExact("SIGNE") ||
 					e.stringAt(-2, "LIGNI", "LIGNO", "REGNA", "DIGNI", "WEGNE", "TIGNE",
@@ -1134,9 +1631,11 @@ func (e *Encoder) encodeGn() bool {
 }
 
 func (e *Encoder) encodeGl() bool {
-	// 'tagliaro', 'puglia' BUT add K in alternative
+	// 'tagliaro', 'puglia', 'gigli' BUT add K in alternative
 	// since americans sometimes do this
-	if e.stringAt(1, "LIA", "LIO", "LIE") && e.isVowelAt(-1) {
+	// exceptions english "uglier", "ugliest"
+	if (e.stringAt(1, "LIA", "LIO", "LIE") || e.stringAtEnd(1, "LI")) && e.isVowelAt(-1) &&
+		!e.stringStart("UGLIER", "UGLIEST") {
 		e.metaphAddExactApproxAlt("L", "GL", "L", "KL")
 		e.idx++
 		return true
@@ -1149,6 +1648,8 @@ func (e *Encoder) encodeInitialGFrontVowel() bool {
 		// special case "gila" as in "gila monster"
 		if e.stringExact("GILA") {
 			e.metaphAdd('H')
+		} else if e.englishHardG() {
+			e.metaphAddExactApprox("G", "K")
 		} else if e.initialGSoft() {
 			e.metaphAddExactApproxAlt("J", "G", "J", "K")
 		} else if e.charNextIs('E') || e.charNextIs('I') {
@@ -1164,6 +1665,13 @@ func (e *Encoder) encodeInitialGFrontVowel() bool {
 	return false
 }
 
+// common english words where 'G' before a front vowel is always hard, and so
+// has no 'J' alternate, e.g. 'get', 'give', 'girl', 'beginning', 'forgive'
+func (e *Encoder) englishHardG() bool {
+	return (e.idx == 0 || e.stringAtStart(-2, "BE", "TO") || e.stringAtStart(-3, "FOR")) &&
+		hardGWords[string(e.in)]
+}
+
 func (e *Encoder) initialGSoft() bool {
 	if (e.stringAt(1, "EL", "EM", "EN", "EO", "ER", "ES", "IA", "IN", "IO", "IP", "IU", "YM", "YN",
 		"YP", "YR", "EE", "IRA", "IRO") &&
@@ -1318,12 +1826,18 @@ func (e *Encoder) encodeNonInitialGFrontVowel() bool {
 			} else {
 				e.metaphAdd('J')
 			}
+		} else if e.stringAt(-2, "LOGY", "LOGIA", "LOGIC", "LOGIS", "LOGIZ", "LOGIES") && !e.stringAt(-3, "BLOG") {
+			// greek "-LOGY" roots are always soft, e.g. 'cardiology', 'logic', but not 'blogistan'
+			e.metaphAdd('J')
+		} else if e.stringAtEnd(1, "EOUS", "IOUS") {
+			// latinate "-GEOUS", "-GIOUS" endings are always soft, e.g. 'gorgeous', 'religious'
+			e.metaphAdd('J')
 		} else {
 			if e.internalHardG() {
 				// don't encode KG or KK if e.g. "mcgill"
 				// todo: should this be !MAC as well?
 				if !e.stringAtStart(-2, "MC") || e.stringAtStart(-3, "MAC") {
-					if e.isSlavoGermanic() {
+					if e.isSlavoGermanic() || e.englishHardG() {
 						e.metaphAddExactApprox("G", "K")
 					} else {
 						e.metaphAddExactApproxAlt("G", "J", "K", "J")
@@ -1442,7 +1956,8 @@ func (e *Encoder) encodeH() {
 
 func (e *Encoder) encodeInitialSilentH() bool {
 	// 'hour', 'herb', 'heir', 'honor'
-	if e.stringAt(1, "OUR", "ERB", "EIR", "ONOR", "ONOUR", "ONEST") {
+	if e.stringAt(1, "OUR", "ERB", "EIR", "ONOR", "ONOUR", "ONEST") &&
+		!(e.PronounceInitialH && e.idx == 0) {
 		// british pronounce H in this word
 		// americans give it 'H' for the name,
 		// no 'H' for the plant
@@ -1477,7 +1992,8 @@ func (e *Encoder) encodeInitialHs() bool {
 
 func (e *Encoder) encodeInitialHuHw() bool {
 	// spanish spellings and chinese pinyin transliteration
-	if e.stringStart("HUA", "HUE", "HWA") && !e.stringAt(0, "HUEY") {
+	// except english 'hue', 'huey'
+	if e.stringStart("HUA", "HUE", "HWA") && !e.stringAt(0, "HUEY") && !e.stringExact("HUE", "HUES", "HUED") {
 		e.metaphAdd('A')
 
 		if !e.EncodeVowels {
@@ -1515,10 +2031,16 @@ func (e *Encoder) encodeHPronounced() bool {
 	if ((e.idx == 0 || e.isVowelAt(-1) || (e.idx > 0 && e.charAt(-1, 'W'))) &&
 		e.isVowelAt(1)) ||
 		// e.g. 'alWahhab'
-		(e.charNextIs('H') && e.isVowelAt(2)) {
+		(e.charNextIs('H') && e.isVowelAt(2)) ||
+		// keep it when the initial vowel before it is dropped, e.g. 'ahmed', 'ihsan',
+		// but not the german lengthening 'H' e.g. 'ehrlich', or 'ohm'
+		(e.DropInitialVowel && e.idx == 1 && e.isVowelAt(-1) && e.idx < e.lastIdx &&
+			!e.charNextIs('R') && !e.stringStart("OHM")) {
 
 		e.metaphAdd('H')
-		e.advanceCounter(1, 0)
+		if !e.EncodeVowels {
+			e.idx = e.skipVowels(e.idx + 1)
+		}
 		return true
 	}
 
@@ -1666,7 +2188,9 @@ func (e *Encoder) encodeSpanishJ2() bool {
 		(e.stringAtEnd(-1, "OJA", "EJA") && !e.stringStart("DEJA")) {
 
 		e.metaphAdd('H')
-		e.advanceCounter(1, 0)
+		if !e.EncodeVowels {
+			e.idx = e.skipVowels(e.idx + 1)
+		}
 		return true
 	}
 
@@ -1735,6 +2259,10 @@ func (e *Encoder) namesBeginningWithJThatGetAltY() bool {
 }
 
 func (e *Encoder) encodeK() {
+	if e.encodeInitialKh() {
+		return
+	}
+
 	if !e.encodeSilentK() {
 		e.metaphAdd('K')
 
@@ -1745,6 +2273,30 @@ func (e *Encoder) encodeK() {
 	}
 }
 
+// Encodes "KH-" at the beginning of transliterations from russian, arabic
+// and others, e.g. "khrushchev", "khan". Americans usually pronounce it 'K'
+// but alternate spellings often drop the 'K', e.g. "hrushchev"
+func (e *Encoder) encodeInitialKh() bool {
+	if !e.stringAtStart(0, "KH") {
+		return false
+	}
+
+	if e.isVowelAt(2) {
+		// except the english 'khaki', which is only 'K'
+		if e.stringStart("KHAKI", "KHAKEE") {
+			return false
+		}
+		e.metaphAddAlt('K', 'H')
+	} else if e.charAt(2, 'R') {
+		// 'H' is silent before 'R', e.g. "hrushchev"
+		e.metaphAddAlt('K', unicode.ReplacementChar)
+	} else {
+		return false
+	}
+	e.idx++
+	return true
+}
+
 func (e *Encoder) encodeSilentK() bool {
 	if e.idx == 0 && e.stringStart("KN") {
 		if !e.stringAt(2, "ISH", "ESSET", "IEVEL") {
@@ -1893,9 +2445,19 @@ func (e *Encoder) encodeSilentLInOuld() bool {
 	return false
 }
 
-//Encode "-ILLA-" and "-ILLE-" in spanish and french contexts were americans
-//know to pronounce it as a 'Y'
+// Encodes the curated cases of "-ILLA-", "-ILLE-" and other "-LL-" in spanish
+// and french words and names where americans know to pronounce it as a 'Y' or
+// leave it silent. Otherwise french "-ILLE" and "-ELLE" endings keep the english
+// 'L', e.g. "camille", "michelle", "bastille"
 func (e *Encoder) encodeLlAsVowelSpecialCases() bool {
+	// the final 'S' is silent too, e.g. "marseilles", "versailles"
+	// exception "bareilles" usually pronounced as 'ba-rel-is'
+	if (e.stringAtEnd(-2, "EILLES") && !e.stringAt(-5, "REVEILLE", "BAREILLE")) ||
+		e.stringAtEnd(-6, "VERSAILLE", "VERSAILLES") {
+		e.idx = e.lastIdx
+		return true
+	}
+
 	if e.stringAt(-5, "TORTILLA") || e.stringAt(-8, "RATATOUILLE") ||
 		// e.g. 'guillermo', "veillard"
 		(e.stringStart("GUILL", "VEILL", "GAILL") &&
@@ -1914,13 +2476,16 @@ func (e *Encoder) encodeLlAsVowelSpecialCases() bool {
 	return false
 }
 
+// Encodes "-LL-" that is a 'Y' glide in spanish or silent in french with
+// an alternate without it, e.g. "cabrillo", "lasalle", "villa"
 func (e *Encoder) encodeLlAsVowel() bool {
 	// spanish e.g. "cabrillo", "gallegos" but also "gorilla", "ballerina" -
 	// give both pronounciations since an american might pronounce "cabrillo"
 	// in the spanish or the american fashion.
 	if e.stringAtEnd(-1, "ILLO", "ILLA", "ALLE") ||
 		(e.stringEnd("A", "O", "AS", "OS") && e.stringAt(-1, "AL", "IL") && !e.stringAt(-1, "ALLA")) ||
-		e.stringStart("LLA", "VILLE", "VILLA", "GALLARDO", "VALLADAR", "MAGALLAN", "CAVALLAR", "BALLASTE") {
+		(e.stringStart("LLA", "VILLE", "VILLA", "GALLARDO", "VALLADAR", "MAGALLAN", "CAVALLAR", "BALLASTE") &&
+			!e.isWelshLlan()) {
 
 		e.metaphAddAlt('L', unicode.ReplacementChar)
 		e.idx++
@@ -1929,6 +2494,13 @@ func (e *Encoder) encodeLlAsVowel() bool {
 	return false
 }
 
+// welsh place names starting "LLAN-" e.g. "llandudno", "llanelli" have an 'L'
+// sound, unlike the spanish 'Y' glide in e.g. "llama", "llano", "llanes"
+func (e *Encoder) isWelshLlan() bool {
+	return e.stringStart("LLAN") && len(e.in) > 4 &&
+		(!isVowel(e.in[4]) || e.stringStart("LLANELL"))
+}
+
 func (e *Encoder) encodeLlAsVowelCases() bool {
 	if e.charNextIs('L') {
 		if e.encodeLlAsVowelSpecialCases() {
@@ -1945,6 +2517,8 @@ func (e *Encoder) encodeLlAsVowelCases() bool {
 func (e *Encoder) encodeVowelLeTransposition(idx int) bool {
 	// transposition of vowel sound and L occurs in many words,
 	// e.g. "bristle", "dazzle", "goggle" => KAKAL
+	// an "-LE" after a consonant is a syllabic L, the vowel is
+	// a schwa spoken before the L rather than after it
 	offset := e.idx - idx
 	if e.EncodeVowels && idx > 1 && !e.isVowelAt(offset-1) && e.charAt(offset+1, 'E') &&
 		!e.charAt(offset-1, 'L') && !e.charAt(offset-1, 'R') &&
@@ -1956,8 +2530,10 @@ func (e *Encoder) encodeVowelLeTransposition(idx int) bool {
 			"LETUS", "LETIV", "LETELY", "LETTER", "LETION", "LETIAN", "LETING", "LETORY", "LETTING") &&
 		// e.g. "complement" !=> KAMPALMENT
 		!(e.stringAt(offset, "LEMENT") &&
-			!(e.stringAt(-4, "BATTLE", "TANGLE", "PUZZLE", "RABBLE", "BABBLE") || e.stringAt(-3, "TABLE"))) &&
-		!(idx+2 == e.lastIdx && e.stringAt(offset-2, "OCLES", "ACLES", "AKLES")) &&
+			!(e.stringAt(-4, "BATTLE", "TANGLE", "PUZZLE", "RABBLE", "BABBLE", "SETTLE", "LITTLE", "BEZZLE", "RUNTLE") ||
+				e.stringAt(-3, "TABLE", "TITLE", "NOBLE"))) &&
+		// greek names, e.g. "sophocles", "heracles", but not "miracles"
+		!(idx+2 == e.lastIdx && (e.stringAt(offset-2, "OCLES", "AKLES") || e.stringStart("HERACLES", "PERICLES"))) &&
 		!e.stringAt(offset-3, "LISLE", "AISLE") && !e.stringStart("ISLE") &&
 		!e.stringStart("ROBLES") &&
 		!e.stringAt(offset-4, "PROBLEM", "RESPLEN") &&
@@ -2011,9 +2587,13 @@ func (e *Encoder) encodeM() {
 		return
 	}
 
-	// Silent 'B' should really be handled
-	// under 'B", not here under 'M'!
-	e.encodeMb()
+	// skip silent 'B', e.g. "lamb", "climbing"
+	if !e.encodeMb() {
+		// skip silent 'N' e.g. "autumn", and redundant 'M'
+		if e.testMn() || e.charNextIs('M') {
+			e.idx++
+		}
+	}
 
 	e.metaphAdd('M')
 }
@@ -2047,7 +2627,9 @@ func (e *Encoder) encodeMrAndMrs() bool {
 func (e *Encoder) encodeMac() bool {
 	// should only find irish and
 	// scottish names e.g. 'macintosh'
-	if e.stringAtStart(0, "MC", "MACIVER", "MACEWEN", "MACELROY", "MACILROY", "MACINTOSH") {
+	// e.g. 'mcadam', 'macinnes' but not 'macy', 'macedonia'
+	if e.stringAtStart(0, "MC", "MACEVOY", "MACIVER", "MACEWEN", "MACELROY", "MACILROY", "MACINNES",
+		"MACINNIS", "MACISAAC", "MACEACHEN", "MACINTOSH", "MACINTYRE", "MACEACHERN") {
 		if e.EncodeVowels {
 			e.metaphAddStr("MAK", "MAK")
 		} else {
@@ -2123,22 +2705,23 @@ func (e *Encoder) testMn() bool {
 		e.stringAt(-5, "GODDAMNIT"))
 }
 
-func (e *Encoder) encodeMb() {
-	if e.testSilentMb1() {
-		if !e.testPronouncedMb() {
-			e.idx++
-		}
-	} else if e.testSilentMb2() {
-		if !e.testPronouncedMb2() {
-			e.idx++
-		}
-	} else if e.testMn() || e.charNextIs('M') {
+// Encodes silent 'B' after 'M', e.g. "lamb", "thumb", "climber", by skipping
+// over it.  This is the only place silent "-MB" is handled: it's done here
+// under 'M' rather than under 'B' so the tests can look at the whole root.
+// A pronounced 'B', e.g. "number", "lumber", "bombastic", is left for encodeB.
+// Returns true if the 'B' was skipped
+func (e *Encoder) encodeMb() bool {
+	if (e.testSilentMb1() && !e.testPronouncedMb()) ||
+		(!e.testSilentMb1() && e.testSilentMb2() && !e.testPronouncedMb2()) {
 		e.idx++
+		return true
 	}
+
+	return false
 }
 
 func (e *Encoder) encodeN() {
-	if e.encodeNce() {
+	if e.encodeVietnameseNg() || e.encodeNce() {
 		return
 	}
 
@@ -2153,6 +2736,34 @@ func (e *Encoder) encodeN() {
 	}
 }
 
+//Encode vietnamese names beginning with "NG-" and "NGH-"
+func (e *Encoder) encodeVietnameseNg() bool {
+	if !e.stringAtStart(0, "NG") {
+		return false
+	}
+
+	// e.g. "nghia", "nghiem", 'GH' is silent
+	if e.charAt(2, 'H') && e.isVowelAt(3) {
+		e.metaphAdd('N')
+		e.idx += 2
+		return true
+	}
+
+	// "nguyen" is usually americanized as 'win' or 'new-win'
+	// so give an alternate without the 'N'
+	if e.stringStart("NGUY", "NGUE") && e.stringEnd("N") {
+		if e.EncodeVowels {
+			e.metaphAddStr("NA", "A")
+		} else {
+			e.metaphAddAlt('N', 'A')
+		}
+		e.idx = e.skipVowels(e.idx + 2)
+		return true
+	}
+
+	return false
+}
+
 //Encode "-NCE-" and "-NSE-" "entrance" is pronounced exactly the same as
 //"entrants"
 func (e *Encoder) encodeNce() bool {
@@ -2169,7 +2780,7 @@ func (e *Encoder) encodeNce() bool {
 }
 
 func (e *Encoder) encodeP() {
-	if e.encodeSilentPAtBeginning() || e.encodePt() || e.encodePh() ||
+	if e.encodeSilentPAtBeginning() || e.encodePt() || e.encodeEpentheticP() || e.encodePh() ||
 		e.encodePph() || e.encodeRps() || e.encodeCoup() ||
 		e.encodePneum() || e.encodePsych() || e.encodePsalm() {
 		return
@@ -2197,6 +2808,17 @@ func (e *Encoder) encodePt() bool {
 	return false
 }
 
+// Encodes "-MPT-" where the 'P' is barely pronounced, e.g. "empty", "tempt",
+// "assumption". The 'P' is kept but there's an alternate without it
+func (e *Encoder) encodeEpentheticP() bool {
+	if e.stringAt(-1, "MPT") {
+		e.metaphAddAlt('P', unicode.ReplacementChar)
+		return true
+	}
+
+	return false
+}
+
 //Encode "-PH-", usually as F, with exceptions for cases where it is silent, or
 //where the 'P' and 'T' are pronounced seperately because they belong to two
 //different words in a combining form
@@ -2206,14 +2828,25 @@ func (e *Encoder) encodePh() bool {
 		if e.stringAt(0, "PHTHALEIN") || e.stringAtStart(0, "PHTH") || e.stringAt(-3, "APOPHTHEGM") {
 			e.metaphAdd('0')
 			e.idx += 3
+		} else if e.idx+1 == e.lastIdx {
+			// word final e.g. 'triumph', 'nymph', 'glyph'
+			e.metaphAdd('F')
+			e.idx++
 		} else if e.idx > 0 &&
-			(e.stringAt(2, "AM", "EAD", "OLE", "ELD", "ILL", "OLD", "EAP", "ERD", "ARD", "ANG",
-				"ORN", "EAV", "ART", "OUSE", "AMMER", "AZARD", "UGGER", "OLSTER") && !e.stringAt(-1, "LPHAM")) &&
-			!e.stringAt(-3, "LYMPH", "NYMPH") {
+			e.isCompoundBoundary(1) && !e.stringAt(-1, "LPHAM") &&
+			!e.stringAt(-3, "LYMPH", "NYMPH") && !e.stringAt(-2, "SEPHARD") {
 			// combining forms
 			// 'sheepherd', 'upheaval', 'cupholder'
 			e.metaphAdd('P')
 			e.advanceCounter(2, 1)
+		} else if e.stringAt(-3, "STEPHEN") && !e.stringAt(-3, "STEPHENI") {
+			// 'stephen' is pronounced 'steven'
+			e.metaphAddExactApprox("V", "F")
+			e.idx++
+		} else if e.stringAt(-2, "NEPHEW") {
+			// british 'nephew' is pronounced 'nevew', the american 'F' is the default
+			e.metaphAddExactApproxAlt("F", "V", "F", "F")
+			e.idx++
 		} else {
 			e.metaphAdd('F')
 			e.idx++
@@ -2225,6 +2858,72 @@ func (e *Encoder) encodePh() bool {
 	return false
 }
 
+// the starts of the second word in compound words where "-PH-", "-SH-", and "-TH-"
+// are pronounced separately, and which of 'P', 'S', and 'T' they follow.
+// e.g. "uphill", "woodshed", "adulthood", "upheaval", "hartsheim", "apartheid"
+var compoundHeads = []struct {
+	head, letters string
+}{
+	{"HOOD", "PST"},
+	{"HEAD", "PST"},
+	{"HOLE", "PST"},
+	{"HILL", "PST"},
+	{"HAWK", "PST"},
+	{"HEAP", "PST"},
+	{"HOOK", "PST"},
+	{"HUNT", "PST"},
+	{"HOUSE", "PST"},
+	{"HEART", "PST"},
+	{"HOUND", "PST"},
+	{"HOLD", "PT"},
+	{"HERD", "PT"},
+	{"HARD", "PT"},
+	{"HEID", "ST"},
+	// 'sheepherd', 'upheaval', 'cupholder'
+	{"HAM", "P"},
+	{"HELD", "P"},
+	{"HANG", "P"},
+	{"HORN", "P"},
+	{"HEAV", "P"},
+	{"HART", "P"},
+	{"HAMMER", "P"},
+	{"HAZARD", "P"},
+	{"HUGGER", "P"},
+	{"HOLSTER", "P"},
+	// 'hartsheim', 'clothshorse', 'dishonor'
+	{"HEIM", "S"},
+	{"HOEK", "S"},
+	{"HOLM", "S"},
+	{"HOLZ", "S"},
+	{"HAAR", "S"},
+	{"HORS", "S"},
+	{"HUND", "S"},
+	{"HELM", "S"},
+	{"HATCH", "S"},
+	{"HONOR", "S"},
+	// 'bithead', 'apartheid'
+	{"HAND", "T"},
+	{"HUMO", "T"},
+	{"HAUS", "T"},
+	{"HOFF", "T"},
+}
+
+// isCompoundBoundary returns true if the second word of a compound word starts at
+// the given offset after the current 'P', 'S', or 'T', e.g. the 'H' in "uphill",
+// "woodshed", or "adulthood"
+func (e *Encoder) isCompoundBoundary(offset int) bool {
+	if e.idx == 0 {
+		return false
+	}
+
+	for _, c := range compoundHeads {
+		if strings.ContainsRune(c.letters, e.in[e.idx]) && e.stringAt(offset, c.head) {
+			return true
+		}
+	}
+	return false
+}
+
 func (e *Encoder) encodePph() bool {
 	// 'sappho'
 	if e.charNextIs('P') && e.idx+2 < len(e.in) && e.charAt(2, 'H') {
@@ -2236,7 +2935,8 @@ func (e *Encoder) encodePph() bool {
 }
 
 func (e *Encoder) encodeRps() bool {
-	// '-corps-', 'corpsman'
+	// french '-corps-', 'corpsman' have a silent 'PS', unlike
+	// 'biceps', 'forceps' and english 'corpse'
 	if e.stringAt(-3, "CORPS") && !e.stringAt(-3, "CORPSE") {
 		e.idx++
 		return true
@@ -2372,6 +3072,8 @@ func (e *Encoder) testSilentR() bool {
 	// test cases where 'R' is silent, either because the
 	// word is from the french or because it is no longer pronounced.
 	// e.g. "rogier", "monsieur", "surburban"
+	// unlike "-IER", the french agent endings "-EUR", "-EUSE" keep the 'R',
+	// e.g. "chauffeur", "connoisseur", so "monsieur" is the only exception
 	if (e.idx == e.lastIdx &&
 		e.stringAt(-2, "IER") &&
 		// e.g. "metier"
@@ -2416,7 +3118,7 @@ func (e *Encoder) encodeVowelReTransposition() bool {
 func (e *Encoder) encodeS() {
 	if e.encodeSkj() || e.encodeSpecialSw() || e.encodeSj() || e.encodeSilentFrenchSFinal() ||
 		e.encodeSilentFrenchSInternal() || e.encodeIsl() || e.encodeStl() || e.encodeChristmas() ||
-		e.encodeSthm() || e.encodeIsten() || e.encodeSugar() || e.encodeSh() || e.encodeSch() ||
+		e.encodeSthm() || e.encodeIsten() || e.encodeSugar() || e.encodeShch() || e.encodeSh() || e.encodeSch() ||
 		e.encodeSur() || e.encodeSu() || e.encodeSsio() || e.encodeSs() || e.encodeSia() ||
 		e.encodeSio() || e.encodeAnglicisations() || e.encodeSc() || e.encodeSeiSuiSier() ||
 		e.encodeSea() {
@@ -2442,13 +3144,13 @@ func (e *Encoder) encodeSkj() bool {
 func (e *Encoder) encodeSpecialSw() bool {
 	if e.idx == 0 {
 		if e.namesBeginningWithSwThatGetAltSv() {
-			e.metaphAddStr("S", "SV")
+			e.metaphAddExactApproxAlt("S", "SV", "S", "SF")
 			e.idx++
 			return true
 		}
 
 		if e.namesBeginningWithSwThatGetAlvXV() {
-			e.metaphAddStr("S", "XV")
+			e.metaphAddExactApproxAlt("S", "XV", "S", "XF")
 			e.idx++
 			return true
 		}
@@ -2596,6 +3298,18 @@ func (e *Encoder) encodeSugar() bool {
 	return false
 }
 
+// Encodes russian transliterations where "-SHCH-" is a single
+// sound, e.g. "khrushchev", "shcherbakov", but not english
+// compounds such as "pushchair"
+func (e *Encoder) encodeShch() bool {
+	if e.stringAtStart(0, "SHCH") || e.stringAt(0, "SHCHE", "SHCHO", "SHCHU") {
+		e.metaphAdd('X')
+		e.idx += 3
+		return true
+	}
+	return false
+}
+
 func (e *Encoder) encodeSh() bool {
 	if e.stringAt(0, "SH") {
 		// exception
@@ -2607,11 +3321,7 @@ func (e *Encoder) encodeSh() bool {
 
 		// combining forms, e.g. 'clotheshorse', 'woodshole'
 		if e.idx > 0 &&
-			(e.stringAtEnd(1, "HAP") ||
-				// e.g. "hartsheim", "clothshorse"
-				// e.g. "dishonor"
-				e.stringAt(1, "HEIM", "HOEK", "HOLM", "HOLZ", "HOOD", "HEAD", "HEID",
-					"HAAR", "HORS", "HOLE", "HUND", "HELM", "HAWK", "HILL", "HEART", "HATCH", "HOUSE", "HOUND", "HONOR") ||
+			(e.stringAtEnd(1, "HAP") || e.isCompoundBoundary(1) ||
 				// e.g. "mishear"
 				e.stringAtEnd(2, "EAR") ||
 				// e.g. "hartshorn"
@@ -2663,6 +3373,9 @@ func (e *Encoder) encodeSch() bool {
 				(e.idx+4 == e.lastIdx || e.stringAt(3, "ENK", "ENB", "IST")) {
 
 				e.metaphAddStr("X", "SK")
+			} else if e.stringAt(0, "SCHISM") {
+				// "schism" is often "sizm"
+				e.metaphAddStr("SK", "S")
 			} else {
 				e.metaphAddStr("SK", "SK")
 			}
@@ -2764,7 +3477,12 @@ func (e *Encoder) encodeSia() bool {
 				"MALTHUS", "HOMOOUS", "MAGLEMOS", "HOMOIOUS",
 				"LEVALLOIS", "TARDENOIS") || e.stringAt(-4, "ALGES")) {
 
-			e.metaphAdd('J')
+			// e.g. 'parisian', 'silesian' can also be "-zee-an"
+			if e.stringAtEnd(-1, "ESIAN", "ISIAN", "YSIAN") {
+				e.metaphAddAlt('J', 'S')
+			} else {
+				e.metaphAdd('J')
+			}
 		} else {
 			e.metaphAdd('S')
 		}
@@ -2879,7 +3597,8 @@ func (e *Encoder) encodeT() {
 	if e.encodeTInitial() || e.encodeTch() || e.encodeSilentFrenchT() ||
 		e.encodeTunTulTuaTuo() || e.encodeTueTeuTeouTulTie() || e.encodeTurTiuSuffixes() ||
 		e.encodeTi() || e.encodeTient() || e.encodeTsch() || e.encodeTzsch() ||
-		e.encodeThPronouncedSeparately() || e.encodeTth() || e.encodeTh() {
+		e.encodeThPronouncedSeparately() || e.encodeTth() || e.encodeTh() ||
+		e.encodeAmericanFlap() {
 		return
 	}
 
@@ -3078,9 +3797,8 @@ func (e *Encoder) encodeTzsch() bool {
 
 func (e *Encoder) encodeThPronouncedSeparately() bool {
 	// 'adulthood', 'bithead', 'apartheid'
-	if (e.idx > 0 && e.stringAt(1, "HOOD", "HEAD", "HEID", "HAND", "HILL", "HOLD", "HAWK", "HEAP", "HERD",
-		"HOLE", "HOOK", "HUNT", "HUMO", "HAUS", "HOFF", "HARD") && !e.stringAt(-3, "SOUTH", "NORTH")) ||
-		e.stringAt(1, "HOUSE", "HEART", "HASTE", "HYPNO", "HEQUE") ||
+	if (e.isCompoundBoundary(1) && !e.stringAt(-3, "SOUTH", "NORTH")) ||
+		e.stringAt(1, "HASTE", "HYPNO", "HEQUE") ||
 		// watch out for greek root "-thallic"
 		(e.stringAtEnd(1, "HALL") && !e.stringAt(-3, "SOUTH", "NORTH")) ||
 		(e.stringAtEnd(1, "HAM") && !e.stringStart("GOTHAM", "WITHAM", "LATHAM", "BENTHAM", "WALTHAM", "WORTHAM", "GRANTHAM")) ||
@@ -3129,13 +3847,14 @@ func (e *Encoder) encodeTh() bool {
 		// special case "thomas", "thames", "beethoven" or germanic words
 		if e.stringAt(2, "OMAS", "OMPS", "OMPK", "OMSO", "OMSE", "AMES", "OVEN", "OFEN", "ILDA", "ILDE") ||
 			e.stringExact("THOM", "THOMS") ||
-			e.stringStart("SCH", "VAN ", "VON ") {
+			// french e.g. "thierry", "thibault", "thibodeaux"
+			e.stringStart("SCH", "VAN ", "VON ", "THIBAU", "THIBOD", "THIERR") {
 
 			e.metaphAdd('T')
 		} else {
 			// give an 'etymological' 2nd
-			// encoding for "smith"
-			if e.stringStart("SM") {
+			// encoding for "smith", and british "antony" for "anthony"
+			if e.stringStart("SM") || (e.stringAt(-2, "ANTHON") && !e.stringAt(-3, "XANTHON")) {
 				e.metaphAddAlt('0', 'T')
 			} else {
 				e.metaphAdd('0')
@@ -3253,7 +3972,9 @@ func (e *Encoder) encodeWh() bool {
 }
 
 func (e *Encoder) encodeEasternEuropeanW() bool {
-	// Arnow should match Arnoff
+	// Arnow should match Arnoff, but a final "-OW" is usually eaten with its vowel
+	// (see skipVowels) so it's a vowel like "meadow" and "borrow", and only polish
+	// "-OWSKI" names e.g. "kozlowski" reliably get the 'F' alternate
 	if (e.idx == e.lastIdx && e.isVowelAt(-1)) ||
 		e.stringAt(-1, "EWSKI", "EWSKY", "OWSKI", "OWSKY") ||
 		e.stringAtEnd(0, "WIAK", "WICKI", "WACKI") ||
@@ -3318,8 +4039,8 @@ func (e *Encoder) encodeX() {
 	}
 
 	// eat redundant 'X' or other redundant cases
-	// e.g. "excite", "exceed"
-	if e.stringAt(1, "X", "Z", "S", "CI", "CE") {
+	// e.g. "excite", "exceed", "excyst"
+	if e.stringAt(1, "X", "Z", "S", "CI", "CE", "CY") {
 		e.idx++
 	}
 }
@@ -3421,6 +4142,8 @@ func (e *Encoder) encodeZz() bool {
 
 func (e *Encoder) encodeZuZierZs() bool {
 	if (e.idx == 1 && e.stringAt(-1, "AZUR")) ||
+		// e.g. 'seizure'
+		(e.stringAtEnd(0, "ZURE", "ZURES") && e.isVowelAt(-1)) ||
 		(e.stringAt(0, "ZIER") && !e.stringAt(-2, "VIZIER")) ||
 		e.stringAt(0, "ZSA") {
 
@@ -3489,7 +4212,7 @@ func (e *Encoder) encodeVowels() {
 			if e.encodeSkipSilentUe() {
 				return
 			}
-			if e.encodeOSilent() {
+			if e.encodeOSilent() || e.encodeIcallyASilent() {
 				return
 			}
 			// encode all vowels and
@@ -3501,18 +4224,43 @@ func (e *Encoder) encodeVowels() {
 		}
 	}
 
-	if !(!e.isVowelAt(-2) && e.stringAt(-1, "LEWA", "LEWO", "LEWI")) {
+	// a run of vowels is a single vowel, e.g. the french "OI" in
+	// 'memoir', 'repertoire' encodes as one 'A', nasal vowels are
+	// plain vowels without an 'N', e.g. the portuguese "ÃO" in 'joão'
+	if !(!e.isVowelAt(-2) && e.stringAt(-1, "LEWA", "LEWO", "LEWI")) &&
+		!(e.PreserveVowelRuns && e.isHiatusEa()) {
 		e.idx = e.skipVowels(e.idx + 1)
 	}
 }
 
+// "-EA-" where each vowel is its own syllable, e.g. "create", "react", "idea",
+// rather than one vowel e.g. "beat", "realm", "creature"
+func (e *Encoder) isHiatusEa() bool {
+	// the 'Y' glide always splits them, e.g. "creyate", "ideya", "abeyance"
+	if e.stringAt(0, "EYA") {
+		return true
+	}
+	if !e.stringAt(0, "EA") {
+		return false
+	}
+
+	return (e.stringAt(-1, "REAL", "REACT", "BEATIF", "REAGEN") && !e.stringAt(-1, "REALM")) ||
+		(e.stringAt(-2, "IDEA", "AREA", "CREAT", "THEAT", "OCEANI", "PREAMB") && !e.stringAt(-2, "CREATU")) ||
+		e.stringAt(-3, "LINEA", "KOREA", "GENEAL") ||
+		e.stringAt(-5, "PANCREA")
+}
+
 func (e *Encoder) encodeSkipSilentUe() bool {
 	// always silent except for cases listed below
 	if (e.stringAt(-1, "QUE", "GUE") &&
 		!e.stringStart("RISQUE", "PIROGUE", "ENRIQUE", "BARBEQUE", "PALENQUE", "APPLIQUE", "COMMUNIQUE") &&
 		!e.stringAt(-3, "ARGUE", "SEGUE")) &&
 		e.idx > 1 &&
-		((e.idx+1 == e.lastIdx) || e.stringStart("JACQUES")) {
+		// e.g. "tongue", "tongues", "plagued"
+		((e.idx+1 == e.lastIdx) || e.stringStart("JACQUES") ||
+			(e.stringAtEnd(-1, "QUES", "GUES", "QUED", "GUED") &&
+				// but not spanish and portuguese names e.g. "vasques", "rodrigues"
+				!e.stringExact(pronouncedEsNames...))) {
 
 		e.idx = e.skipVowels(e.idx)
 		return true
@@ -3570,6 +4318,12 @@ func (e *Encoder) encodeOSilent() bool {
 	return false
 }
 
+func (e *Encoder) encodeIcallyASilent() bool {
+	// the 'A' in adverbs ending "-ICALLY" isn't spoken,
+	// e.g. "basically" like "basicly" => PASAKLA
+	return e.stringAtEnd(-2, "ICALLY")
+}
+
 func (e *Encoder) encodeESilent() bool {
 	if e.encodeEPronouncedAtEnd() {
 		return false
@@ -3639,6 +4393,11 @@ func (e *Encoder) encodeEPronouncedAtEnd() bool {
 }
 
 func (e *Encoder) encodeSilentInternalE() bool {
+	// not french plurals e.g. 'gateaux'
+	if e.stringAtEnd(0, "EAUX") {
+		return false
+	}
+
 	// 'olesen' but not 'olen'	RAKE BLAKE
 	if (e.stringStart("OLE") && e.encodeESuffix(3)) ||
 		(e.stringStart("BARE", "FIRE", "FORE", "GATE", "HAGE", "HAVE",
@@ -3687,26 +4446,33 @@ func (e *Encoder) encodeESuffix(at int) bool {
 // usually wouldn't be, and also some cases
 // where 'LE' transposition rules don't apply
 // and the vowel needs to be encoded here
+// hispanic and greek names ending in "-ES" where the 'e' is pronounced, including
+// spanish and portuguese "-QUES" and "-GUES" names e.g. "vasques", "rodrigues".
+// Ordered by length, shortest to longest.
+var pronouncedEsNames = []string{"INES",
+	"LOPES", "ESTES", "GOMES", "NUNES", "ALVES", "ICKES",
+	"INNES", "PERES", "WAGES", "NEVES", "BENES", "DONES",
+	"CORTES", "CHAVES", "VALDES", "ROBLES", "TORRES", "FLORES", "BORGES",
+	"NIEVES", "MONTES", "SOARES", "VALLES", "GEDDES", "ANDRES", "VIAJES",
+	"CALLES", "FONTES", "HERMES", "ACEVES", "BATRES", "MATHES", "MIGUES", "YAGUES",
+	"DELORES", "MORALES", "DOLORES", "ANGELES", "ROSALES", "MIRELES", "LINARES",
+	"PERALES", "PAREDES", "BRIONES", "SANCHES", "CAZARES", "REVELES", "ESTEVES",
+	"ALVARES", "MATTHES", "SOLARES", "CASARES", "CACERES", "STURGES", "RAMIRES",
+	"FUNCHES", "BENITES", "FUENTES", "PUENTES", "TABARES", "HENTGES", "VALORES",
+	"MARQUES", "VASQUES", "VAZQUES",
+	"GONZALES", "MERCEDES", "FAGUNDES", "JOHANNES", "GONSALES", "BERMUDES",
+	"CESPEDES", "BETANCES", "TERRONES", "DIOGENES", "CORRALES", "CABRALES",
+	"MARTINES", "GRAJALES", "HERACLES", "PERICLES",
+	"CERVANTES", "FERNANDES", "GONCALVES", "BENEVIDES", "CIFUENTES", "SIFUENTES",
+	"SERVANTES", "HERNANDES", "BENAVIDES", "RODEIGUES", "RODREGUES", "RODRIGUES",
+	"RODRIQUES", "HENRIQUES", "DOMINGUES", "VELASQUES", "VELAZQUES",
+	"ARCHIMEDES", "CARRIZALES", "MAGALLANES", "RODERIQUES"}
+
 func (e *Encoder) encodeEPronouncedExceptions() bool {
 	// greek names e.g. "herakles" or hispanic names e.g. "robles", where 'e' is pronounced, other exceptions
 	if (e.idx+1 == e.lastIdx &&
-		(e.stringAtEnd(-3, "OCLES", "ACLES", "AKLES") ||
-			e.stringStart("INES",
-				"LOPES", "ESTES", "GOMES", "NUNES", "ALVES", "ICKES",
-				"INNES", "PERES", "WAGES", "NEVES", "BENES", "DONES",
-				"CORTES", "CHAVES", "VALDES", "ROBLES", "TORRES", "FLORES", "BORGES",
-				"NIEVES", "MONTES", "SOARES", "VALLES", "GEDDES", "ANDRES", "VIAJES",
-				"CALLES", "FONTES", "HERMES", "ACEVES", "BATRES", "MATHES",
-				"DELORES", "MORALES", "DOLORES", "ANGELES", "ROSALES", "MIRELES", "LINARES",
-				"PERALES", "PAREDES", "BRIONES", "SANCHES", "CAZARES", "REVELES", "ESTEVES",
-				"ALVARES", "MATTHES", "SOLARES", "CASARES", "CACERES", "STURGES", "RAMIRES",
-				"FUNCHES", "BENITES", "FUENTES", "PUENTES", "TABARES", "HENTGES", "VALORES",
-				"GONZALES", "MERCEDES", "FAGUNDES", "JOHANNES", "GONSALES", "BERMUDES",
-				"CESPEDES", "BETANCES", "TERRONES", "DIOGENES", "CORRALES", "CABRALES",
-				"MARTINES", "GRAJALES",
-				"CERVANTES", "FERNANDES", "GONCALVES", "BENEVIDES", "CIFUENTES", "SIFUENTES",
-				"SERVANTES", "HERNANDES", "BENAVIDES",
-				"ARCHIMEDES", "CARRIZALES", "MAGALLANES"))) ||
+		(e.stringAtEnd(-3, "OCLES", "AKLES") ||
+			e.stringStart(pronouncedEsNames...))) ||
 		e.stringAt(-2, "FRED", "DGES", "DRED", "GNES") ||
 		e.stringAt(-5, "PROBLEM", "RESPLEN") ||
 		e.stringAt(-4, "REPLEN") ||
@@ -4039,7 +4805,7 @@ func (e *Encoder) metaphAdd(in rune) {
 func (e *Encoder) metaphAddAlt(prim, second rune) {
 	if prim != unicode.ReplacementChar {
 		// don't dupe added A's
-		if !(prim == 'A' && len(e.primBuf) > 0 && e.primBuf[len(e.primBuf)-1] == 'A') {
+		if !e.skipA(e.primBuf, prim) {
 			if debug {
 				fmt.Printf("Append Prim: %v at %v\n", string(prim), string(e.in[0:e.idx+1]))
 			}
@@ -4049,7 +4815,7 @@ func (e *Encoder) metaphAddAlt(prim, second rune) {
 
 	if second != unicode.ReplacementChar {
 		// don't dupe added A's
-		if !(second == 'A' && len(e.secondBuf) > 0 && e.secondBuf[len(e.secondBuf)-1] == 'A') {
+		if !e.skipA(e.secondBuf, second) {
 			if debug {
 				fmt.Printf("Append Alt: %v at %v\n", string(second), string(e.in[0:e.idx+1]))
 			}
@@ -4061,7 +4827,7 @@ func (e *Encoder) metaphAddAlt(prim, second rune) {
 // Adds given strings to the associated encoded strings
 func (e *Encoder) metaphAddStr(prim, second string) {
 	// don't dupe added A's
-	if !(prim == "A" && len(e.primBuf) > 0 && e.primBuf[len(e.primBuf)-1] == 'A') {
+	if !(prim == "A" && e.skipA(e.primBuf, 'A')) {
 		if debug {
 			fmt.Printf("Append Prim: %v at %v\n", prim, string(e.in[0:e.idx+1]))
 		}
@@ -4069,7 +4835,7 @@ func (e *Encoder) metaphAddStr(prim, second string) {
 	}
 
 	// don't dupe added A's
-	if second != "" && !(second == "A" && len(e.secondBuf) > 0 && e.secondBuf[len(e.secondBuf)-1] == 'A') {
+	if second != "" && !(second == "A" && e.skipA(e.secondBuf, 'A')) {
 		if debug {
 			fmt.Printf("Append Alt: %v at %v\n", second, string(e.in[0:e.idx+1]))
 		}
@@ -4077,6 +4843,18 @@ func (e *Encoder) metaphAddStr(prim, second string) {
 	}
 }
 
+// skipA returns true if r is an 'A' that shouldn't be added to buf, either because
+// it would follow another 'A' or because it's the initial vowel and DropInitialVowel is set
+func (e *Encoder) skipA(buf []rune, r rune) bool {
+	if r != 'A' {
+		return false
+	}
+	if len(buf) == 0 {
+		return e.DropInitialVowel
+	}
+	return !e.PreserveVowelRuns && buf[len(buf)-1] == 'A'
+}
+
 func (e *Encoder) metaphAddExactApproxAlt(exact, altExact, main, alt string) {
 	if e.EncodeExact {
 		e.metaphAddStr(exact, altExact)
@@ -4161,6 +4939,148 @@ func areEqual(buf1 []rune, buf2 []rune) bool {
 	return true
 }
 
+func isApostrophe(r rune) bool {
+	return r == '\'' || r == '’'
+}
+
+// words where a following "'S" is a contraction of "is", "has", or "us"
+// that is pronounced, e.g. "IT'S", rather than a possessive
+var sContractions = map[string]bool{
+	"IT": true, "HE": true, "SHE": true, "LET": true, "THAT": true, "WHAT": true,
+	"WHO": true, "HOW": true, "HERE": true, "THERE": true, "WHERE": true, "WHEN": true,
+}
+
+// removes leading apostrophes and the possessive clitic from the uppercased
+// input, e.g. "'TWAS" => "TWAS", "JOHN'S" => "JOHN", "DOGS'" => "DOGS".
+// For contractions only the apostrophe is removed, e.g. "IT'S" => "ITS"
+func stripClitics(in []rune) []rune {
+	for len(in) > 0 && isApostrophe(in[0]) {
+		in = in[1:]
+	}
+
+	if len(in) > 2 && in[len(in)-1] == 'S' && isApostrophe(in[len(in)-2]) {
+		if sContractions[string(in[:len(in)-2])] {
+			in = append(in[:len(in)-2], 'S')
+		} else {
+			in = in[:len(in)-2]
+		}
+	}
+	for len(in) > 0 && isApostrophe(in[len(in)-1]) {
+		in = in[:len(in)-1]
+	}
+
+	return in
+}
+
+// shortens a reduplicated ending, a run of 2 to 4 letters repeated at least
+// twice, down to one repeat, e.g. "HAHAHA" => "HA", "LOLOLOL" => "LOL",
+// "MUAHAHAHA" => "MUAHA"
+func collapseReduplication(in []rune) []rune {
+	for p := 0; p < len(in); p++ {
+		tail := in[p:]
+		for u := 2; u <= 4; u++ {
+			if len(tail) < 2*u {
+				break
+			}
+			if isRepeatOf(tail, u) {
+				return in[:p+u+len(tail)%u]
+			}
+		}
+	}
+	return in
+}
+
+// true if buf is made of its first n letters repeated, the last repeat may
+// be cut short
+func isRepeatOf(buf []rune, n int) bool {
+	for i := n; i < len(buf); i++ {
+		if buf[i] != buf[i-n] || !unicode.IsLetter(buf[i]) {
+			return false
+		}
+	}
+	return true
+}
+
+// the name suffixes removed by StripNameSuffixes, without periods
+var nameSuffixes = map[string]bool{
+	"JR": true, "SR": true,
+	"II": true, "III": true, "IV": true, "V": true, "VI": true,
+	"VII": true, "VIII": true, "IX": true, "X": true,
+	"PHD": true, "MD": true, "DDS": true, "ESQ": true,
+}
+
+// removes trailing name suffixes from the uppercased input, e.g.
+// "JOHN SMITH, JR." => "JOHN SMITH"
+func stripNameSuffixes(in []rune) []rune {
+	isSep := func(r rune) bool { return r == ' ' || r == ',' }
+
+	for {
+		end := len(in)
+		for end > 0 && (isSep(in[end-1]) || in[end-1] == '.') {
+			end--
+		}
+
+		start := end
+		for start > 0 && !isSep(in[start-1]) {
+			start--
+		}
+
+		// keep at least one word
+		prev := start
+		for prev > 0 && isSep(in[prev-1]) {
+			prev--
+		}
+		if prev == 0 {
+			return in
+		}
+
+		word := strings.Replace(string(in[start:end]), ".", "", -1)
+		if !nameSuffixes[word] {
+			return in
+		}
+
+		in = in[:prev]
+	}
+}
+
+// shortens runs of three or more of the same letter down to two in inputs
+// without vowels, e.g. "HMMM" => "HMM", "BRRRR" => "BRR", so that drawn out
+// interjections match their usual spelling
+func collapseDrawnOut(in []rune) []rune {
+	for _, r := range in {
+		if isVowel(r) {
+			return in
+		}
+	}
+
+	out := make([]rune, 0, len(in))
+	for i, r := range in {
+		if i >= 2 && r == in[i-1] && r == in[i-2] {
+			continue
+		}
+		out = append(out, r)
+	}
+	return out
+}
+
+// lowercases the buffer in place, non-letters such as '0' are unchanged
+func toLower(buf []rune) {
+	for i, r := range buf {
+		buf[i] = unicode.ToLower(r)
+	}
+}
+
+// right-pads a non-blank buffer to minLen with padChar
+func pad(buf []rune, minLen int, padChar rune) []rune {
+	if len(buf) == 0 {
+		return buf
+	}
+	for len(buf) < minLen {
+		buf = append(buf, padChar)
+	}
+	return buf
+}
+
 // make sure we have capacity for our whole buffer, but 0 len
 func primeBuf(buf []rune, ensureCap int) []rune {
 	if want := ensureCap - cap(buf); want > 0 {
@@ -4171,3 +5091,92 @@ func primeBuf(buf []rune, ensureCap int) []rune {
 
 	return buf
 }
+
+// the spelled out names of the letters used by SpellAcronyms
+var letterNames = map[rune]string{
+	'A': "AY", 'B': "BEE", 'C': "SEE", 'D': "DEE", 'E': "EE", 'F': "EF", 'G': "JEE",
+	'H': "AITCH", 'I': "EYE", 'J': "JAY", 'K': "KAY", 'L': "EL", 'M': "EM", 'N': "EN",
+	'O': "OH", 'P': "PEE", 'Q': "CUE", 'R': "AR", 'S': "ES", 'T': "TEE", 'U': "YOU",
+	'V': "VEE", 'W': "DOUBLEYOU", 'X': "EX", 'Y': "WYE", 'Z': "ZEE",
+}
+
+// the spelled out names of the symbols used by SpellSymbols
+var symbolNames = map[rune]string{
+	'&': "AND", '@': "AT", '%': "PERCENT", '+': "PLUS",
+}
+
+// spells out each symbol as a separate word, e.g. "AT&T" => "AT AND T"
+func spellSymbols(in string) string {
+	if !strings.ContainsAny(in, "&@%+") {
+		return in
+	}
+
+	var sb strings.Builder
+	for _, r := range in {
+		if name, ok := symbolNames[r]; ok {
+			sb.WriteString(" " + name + " ")
+		} else {
+			sb.WriteRune(r)
+		}
+	}
+	return strings.TrimSpace(sb.String())
+}
+
+// returns true if the raw input looks like an acronym, e.g. "FBI", "HTML"
+func isAcronym(in string) bool {
+	if len(in) < 2 || len(in) > 5 {
+		return false
+	}
+
+	vowels := 0
+	for _, r := range in {
+		if r < 'A' || r > 'Z' {
+			return false
+		}
+		if isVowel(r) {
+			vowels++
+		}
+	}
+
+	// words like "NO", "IT", and "JOE" are more likely than acronyms
+	return vowels == 0 || vowelAcronyms[in]
+}
+
+// common acronyms with vowels that are spelled out rather than read as a word
+var vowelAcronyms = map[string]bool{
+	"AI": true, "EU": true, "UK": true, "UN": true,
+	"ABC": true, "AKA": true, "ATM": true, "CEO": true, "CFO": true, "CIA": true,
+	"CPU": true, "EPA": true, "FAA": true, "FBI": true, "FDA": true, "GPA": true,
+	"HIV": true, "IBM": true, "IOU": true, "IRA": true, "IRS": true, "NBA": true,
+	"NSA": true, "UAE": true, "USA": true, "USB": true, "VIP": true,
+}
+
+// whole words and their inflections with a hard 'G' before a front vowel, see
+// englishHardG. Names like "getty", "geary" and "givens" aren't listed, and
+// neither are "begin" and "forget" since they're also french surnames.
+var hardGWords = map[string]bool{
+	"GET": true, "GETS": true, "GETTING": true, "GETAWAY": true, "GETAWAYS": true,
+	"BEGET": true, "BEGETS": true, "BEGETTING": true,
+	"FORGETS": true, "FORGETTING": true, "FORGETFUL": true, "FORGETFULNESS": true, "FORGETTABLE": true,
+	"TOGETHER": true, "TOGETHERNESS": true,
+	"BEGINS": true, "BEGINNER": true, "BEGINNERS": true, "BEGINNING": true, "BEGINNINGS": true,
+	"GEAR": true, "GEARS": true, "GEARED": true, "GEARING": true, "GEARBOX": true, "GEARBOXES": true,
+	"GIFT": true, "GIFTS": true, "GIFTED": true, "GIFTING": true,
+	"GIVE": true, "GIVES": true, "GIVEN": true, "GIVER": true, "GIVERS": true, "GIVING": true,
+	"GIVEAWAY": true, "GIVEAWAYS": true,
+	"FORGIVE": true, "FORGIVES": true, "FORGIVEN": true, "FORGIVENESS": true, "FORGIVING": true,
+	"GIRL": true, "GIRLS": true, "GIRLISH": true, "GIRLHOOD": true, "GIRLFRIEND": true, "GIRLFRIENDS": true,
+	"GECKO": true, "GECKOS": true, "GEESE": true,
+	"GIGGLE": true, "GIGGLES": true, "GIGGLED": true, "GIGGLING": true, "GIGGLY": true,
+	"GIRTH": true, "GIRTHS": true, "GEYSER": true, "GEYSERS": true,
+	"GIMMICK": true, "GIMMICKS": true, "GIMMICKY": true, "GIZZARD": true, "GIZZARDS": true,
+}
+
+// spells out each letter of an acronym, e.g. "FBI" => "EF BEE EYE"
+func spellLetters(in string) string {
+	names := make([]string, 0, len(in))
+	for _, r := range in {
+		names = append(names, letterNames[r])
+	}
+	return strings.Join(names, " ")
+}
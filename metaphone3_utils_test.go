@@ -1,6 +1,10 @@
 package metaphone3
 
-import "testing"
+import (
+	"fmt"
+	"strings"
+	"testing"
+)
 
 func TestStringAt_Basic(t *testing.T) {
 	if want, got := true, testStringAt("TESTING", 0, 1, "B", "E"); want != got {
@@ -41,6 +45,35 @@ func TestRootOrInflections_Ache(t *testing.T) {
 	}
 }
 
+func TestIsCompoundBoundary(t *testing.T) {
+	vals := []struct {
+		in     string
+		curIdx int
+		want   bool
+	}{
+		{"UPHILL", 1, true},
+		{"ADULTHOOD", 4, true},
+		{"WOODSHOUSE", 4, true},
+		{"LOOPHOLE", 3, true},
+		{"THOUSE", 0, false},
+		{"ALPHABET", 2, false},
+		// only after the letters they're listed for
+		{"UPHEAVAL", 1, true},
+		{"HARTSHEIM", 4, true},
+		{"HOTHEIM", 2, false},
+		{"OFFHAND", 2, false},
+	}
+
+	for _, v := range vals {
+		e := &Encoder{}
+		e.in = []rune(v.in)
+		e.idx = v.curIdx
+		if got := e.isCompoundBoundary(1); got != v.want {
+			t.Errorf("isCompoundBoundary(%v at %v) wanted %v, got %v", v.in, v.curIdx, v.want, got)
+		}
+	}
+}
+
 func testStringAt(in string, curIdx, offset int, vals ...string) bool {
 	e := &Encoder{}
 	e.in = []rune(in)
@@ -61,3 +94,45 @@ func testStringAtEnd(in string, curIdx, offset int, vals ...string) bool {
 	e.idx = curIdx
 	return e.stringAtEnd(offset, vals...)
 }
+
+// checkKey returns an error if a metaphone from the encoder is malformed: it's
+// longer than maxLen, has characters outside the OutputAlphabet, or (unless
+// vowel runs are allowed) has consecutive 'A's
+func checkKey(key string, maxLen int, allowVowelRuns bool) error {
+	if n := len([]rune(key)); n > maxLen {
+		return fmt.Errorf("length %v is more than %v", n, maxLen)
+	}
+	for _, r := range key {
+		if !ValidSymbol(r) {
+			return fmt.Errorf("%q is not in the OutputAlphabet", r)
+		}
+	}
+	if !allowVowelRuns && strings.Contains(strings.ToUpper(key), "AA") {
+		return fmt.Errorf("has consecutive A's")
+	}
+	return nil
+}
+
+func TestCheckKey(t *testing.T) {
+	vals := []struct {
+		key      string
+		maxLen   int
+		runs, ok bool
+	}{
+		{"SM0", 8, false, true},
+		{"", 8, false, true},
+		{"sm0", 8, false, true},
+		{"SAPARNAT", 8, false, true},
+		{"SAPARNATS", 8, false, false},
+		{"SMI0", 8, false, false},
+		{"SM 0", 8, false, false},
+		{"HAAR", 8, false, false},
+		{"HAAR", 8, true, true},
+	}
+
+	for _, v := range vals {
+		if err := checkKey(v.key, v.maxLen, v.runs); (err == nil) != v.ok {
+			t.Errorf("checkKey(%q, %v, %v) wanted ok=%v, got %v", v.key, v.maxLen, v.runs, v.ok, err)
+		}
+	}
+}
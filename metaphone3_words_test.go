@@ -1,11 +1,14 @@
 package metaphone3
 
 import (
+	"bytes"
 	"encoding/csv"
+	"hash/fnv"
 	"io"
 	"io/ioutil"
 	"os"
 	"path/filepath"
+	"strings"
 	"testing"
 )
 
@@ -125,3 +128,2401 @@ func encodeSafe(t *testing.T, name string, e *Encoder, in, main, alt string, err
 	}
 
 }
+
+func TestSameSound(t *testing.T) {
+	vals := []struct {
+		a, b string
+		want bool
+	}{
+		{"Smith", "Smyth", true},
+		// primary of one matches secondary of the other
+		{"Smith", "Schmidt", true},
+		{"Schmidt", "Smith", true},
+		// secondaries match
+		{"Jones", "Jonas", true},
+		{"Smith", "Jones", false},
+		{"", "", false},
+		{"Smith", "", false},
+	}
+	e := &Encoder{}
+
+	for _, v := range vals {
+		if got := e.SameSound(v.a, v.b); got != v.want {
+			t.Errorf("SameSound('%v', '%v') wanted %v, got %v", v.a, v.b, v.want, got)
+		}
+	}
+}
+
+func TestVietnameseNg(t *testing.T) {
+	testSoundsAlike(t, [][2]string{
+		{"Nguyen", "Win"},
+		{"Nguyen", "Newin"},
+		{"Nguyen", "Nguyn"},
+		{"Nghia", "Nia"},
+	})
+}
+
+// allEncoders returns one encoder for each combination of EncodeVowels and EncodeExact,
+// in the column order of the testdata files
+func allEncoders() []*Encoder {
+	return []*Encoder{
+		{},
+		{EncodeVowels: true, EncodeExact: true},
+		{EncodeExact: true},
+		{EncodeVowels: true},
+	}
+}
+
+// GenerateTestData writes the testdata ".test" lines for the words to w, so the
+// expected output can be regenerated after a verified fix and then diffed
+func GenerateTestData(words []string, w io.Writer) {
+	io.WriteString(w, GenerateGolden(words))
+}
+
+// testSoundsAlike checks that each pair shares a metaphone with every encoder configuration
+func testSoundsAlike(t *testing.T, pairs [][2]string) {
+	for _, e := range allEncoders() {
+		for _, p := range pairs {
+			if !e.SameSound(p[0], p[1]) {
+				p1, s1 := e.Encode(p[0])
+				p2, s2 := e.Encode(p[1])
+				t.Errorf("Expected '%v' (%v, %v) and '%v' (%v, %v) to match with vowels=%v exact=%v",
+					p[0], p1, s1, p[1], p2, s2, e.EncodeVowels, e.EncodeExact)
+			}
+		}
+	}
+}
+
+func TestBritishPlaces(t *testing.T) {
+	testSoundsAlike(t, [][2]string{
+		{"Norwich", "Norridge"},
+		{"Greenwich", "Grenitch"},
+		{"Greenwich", "Grenidge"},
+		{"Harwich", "Harridge"},
+		{"Warwick", "Worrick"},
+		{"Berwick", "Berrick"},
+		{"Keswick", "Kezzick"},
+	})
+}
+
+func TestPhAndF(t *testing.T) {
+	testSoundsAlike(t, [][2]string{
+		{"Philip", "Filip"},
+		{"Phillip", "Filip"},
+		{"Stephen", "Steven"},
+		{"Stephens", "Stevens"},
+		{"Stephan", "Stefan"},
+		{"Joseph", "Josef"},
+		{"Ralph", "Ralf"},
+		{"Rudolph", "Rudolf"},
+		{"Sophia", "Sofia"},
+		{"Christopher", "Kristofer"},
+	})
+}
+
+func TestEncodeHash(t *testing.T) {
+	e := &Encoder{}
+
+	prim, second := e.EncodeHash("Smith")
+	// FNV-1a of "SM0" and "XMT", these must never change
+	if want := uint64(0x97f27b19fa54c597); prim != want {
+		t.Errorf("Invalid primary hash, wanted %x, got %x", want, prim)
+	}
+	if want := uint64(0xd2bfb81a1b1e0afe); second != want {
+		t.Errorf("Invalid secondary hash, wanted %x, got %x", want, second)
+	}
+
+	if _, second := e.EncodeHash("Schmidt"); second != 0 {
+		t.Errorf("Expected blank secondary to hash to 0, got %x", second)
+	}
+
+	if prim, _ := e.EncodeHash("Schmidt"); prim != hashKey("XMT") {
+		t.Errorf("Expected 'Schmidt' primary to hash like 'Smith' secondary")
+	}
+
+	// must match the standard library's FNV-1a
+	for _, key := range []string{"A", "SM0", "XMT", "KRXF", "ANTRNXNL"} {
+		h := fnv.New64a()
+		h.Write([]byte(key))
+		if want := h.Sum64(); hashKey(key) != want {
+			t.Errorf("Invalid hash for '%v', wanted %x, got %x", key, want, hashKey(key))
+		}
+	}
+}
+
+func TestRhoticHomophones(t *testing.T) {
+	testSoundsAlike(t, [][2]string{
+		{"fair", "fare"},
+		{"fairs", "fares"},
+		{"pear", "pair"},
+		{"pair", "pare"},
+		{"paired", "pared"},
+		{"bear", "bare"},
+		{"hair", "hare"},
+		{"stair", "stare"},
+		{"stairs", "stares"},
+		{"their", "there"},
+		{"flair", "flare"},
+		{"air", "heir"},
+		{"fibre", "fiber"},
+		{"centre", "center"},
+		{"theatre", "theater"},
+	})
+}
+
+func TestWichWickWicz(t *testing.T) {
+	testSoundsAlike(t, [][2]string{
+		{"Greenwich", "Grenidge"},
+		{"Greenwich", "Greenwitch"},
+		{"Ipswich", "Ipswitch"},
+		{"Warwick", "Warrick"},
+		{"Brunswick", "Brunsick"},
+		{"Filipowicz", "Filipowitz"},
+	})
+
+	// english "-WICH" shouldn't pick up the germanic 'K'
+	e := &Encoder{}
+	if e.SameSound("Ipswich", "Ipswick") {
+		t.Errorf("Expected 'Ipswich' and 'Ipswick' to not match")
+	}
+}
+
+func TestFinalPh(t *testing.T) {
+	testSoundsAlike(t, [][2]string{
+		{"graph", "graf"},
+		{"nymph", "nimf"},
+		{"glyph", "glif"},
+		{"lymph", "limf"},
+		{"morph", "morf"},
+		{"triumph", "triumf"},
+	})
+}
+
+func TestRussianTransliterations(t *testing.T) {
+	testSoundsAlike(t, [][2]string{
+		{"tchaikovsky", "chaikovsky"},
+		{"khrushchev", "hrushchev"},
+		{"khrushchev", "krushchev"},
+		{"gorbachev", "gorbachov"},
+		{"zhukov", "jukov"},
+		{"khodorkovsky", "hodorkovsky"},
+		{"chekhov", "tchekhov"},
+	})
+
+	// the english 'khaki' has no 'H' alternate
+	e := &Encoder{}
+	if prim, sec := e.Encode("khaki"); prim != "KK" || sec != "" {
+		t.Errorf("Expected 'khaki' to be KK, got %v %v", prim, sec)
+	}
+}
+
+func TestSureVoicing(t *testing.T) {
+	// voiced /ʒ/ => J, unvoiced /ʃ/ => X
+	tests := []struct {
+		in, prim string
+	}{
+		{"sure", "XR"},
+		{"ensure", "ANXR"},
+		{"pressure", "PRXR"},
+		{"fissure", "FXR"},
+		{"sugar", "XKR"},
+		{"measure", "MJR"},
+		{"pleasure", "PLJR"},
+		{"treasure", "TRJR"},
+		{"leisure", "LJR"},
+		{"closure", "KLJR"},
+		{"exposure", "AKSPJR"},
+		{"erasure", "ARJR"},
+		{"usury", "AJR"},
+		{"azure", "AJR"},
+		{"seizure", "SJR"},
+	}
+
+	e := &Encoder{}
+	for _, tt := range tests {
+		if prim, _ := e.Encode(tt.in); prim != tt.prim {
+			t.Errorf("%v: expected %v, got %v", tt.in, tt.prim, prim)
+		}
+	}
+
+	// "-SURE" after a vowel is 'J'
+	testSoundsAlike(t, [][2]string{
+		{"pleasure", "plezhur"},
+		{"measure", "mezhur"},
+		{"treasure", "trezhur"},
+		{"leisure", "lezhur"},
+		{"azure", "azhur"},
+		{"erasure", "erazhur"},
+		{"closure", "klozhur"},
+		{"seizure", "seezhur"},
+	})
+
+	// but 'X' after a consonant or double 'S'
+	testSoundsAlike(t, [][2]string{
+		{"insure", "inshur"},
+		{"censure", "senshur"},
+		{"pressure", "preshur"},
+		{"fissure", "fishur"},
+	})
+}
+
+func TestAmericanFlap(t *testing.T) {
+	pairs := [][2]string{
+		{"latter", "ladder"},
+		{"metal", "medal"},
+		{"writer", "rider"},
+		{"water", "wader"},
+		{"atom", "adam"},
+	}
+
+	// exact keeps them apart without the flap
+	e := &Encoder{EncodeExact: true}
+	for _, p := range pairs {
+		if e.SameSound(p[0], p[1]) {
+			t.Errorf("Expected '%v' and '%v' to differ without AmericanFlap", p[0], p[1])
+		}
+	}
+
+	for _, e := range allEncoders() {
+		e.AmericanFlap = true
+		for _, p := range pairs {
+			if !e.SameSound(p[0], p[1]) {
+				t.Errorf("Expected '%v' and '%v' to match with vowels=%v exact=%v",
+					p[0], p[1], e.EncodeVowels, e.EncodeExact)
+			}
+		}
+	}
+
+	// no flap before a silent 'E' or after a consonant
+	e = &Encoder{EncodeExact: true, AmericanFlap: true}
+	for _, p := range [][2]string{{"bate", "bade"}, {"after", "adder"}} {
+		if e.SameSound(p[0], p[1]) {
+			t.Errorf("Expected '%v' and '%v' to differ with AmericanFlap", p[0], p[1])
+		}
+	}
+
+	// the flap doesn't replace a 'D' pronounced as 'J'
+	for _, e := range allEncoders() {
+		for _, w := range []string{"module", "education", "individual", "graduate", "procedure"} {
+			m1, s1 := e.Encode(w)
+			e.AmericanFlap = true
+			m2, s2 := e.Encode(w)
+			e.AmericanFlap = false
+			if m1 != m2 || s1 != s2 {
+				t.Errorf("Expected '%v' to encode as (%v, %v) with AmericanFlap, got (%v, %v) with vowels=%v exact=%v",
+					w, m1, s1, m2, s2, e.EncodeVowels, e.EncodeExact)
+			}
+		}
+	}
+}
+
+func TestOusUs(t *testing.T) {
+	testSoundsAlike(t, [][2]string{
+		{"famous", "famus"},
+		{"nervous", "nervus"},
+		{"marcus", "markous"},
+		{"bogus", "bogous"},
+		{"jealous", "jelus"},
+		{"pious", "pius"},
+		{"curious", "cureus"},
+		{"callous", "callus"},
+		{"tremendous", "tremendus"},
+	})
+}
+
+func TestGermanSurnames(t *testing.T) {
+	testSoundsAlike(t, [][2]string{
+		{"smith", "schmidt"},
+		{"smith", "schmitt"},
+		{"smit", "schmidt"},
+		{"snider", "schneider"},
+		{"snyder", "schneider"},
+		{"slater", "schlater"},
+		{"slosser", "schlosser"},
+		{"swartz", "schwarz"},
+		{"switzer", "schweitzer"},
+		{"schultz", "shultz"},
+		{"schroeder", "shroeder"},
+		{"schaefer", "shafer"},
+		{"schuster", "shuster"},
+		{"swanson", "svanson"},
+		{"swoboda", "svoboda"},
+	})
+}
+
+func TestFrenchLl(t *testing.T) {
+	// english 'L'
+	testSoundsAlike(t, [][2]string{
+		{"camille", "camil"},
+		{"michelle", "michel"},
+		{"danielle", "daniel"},
+		{"gabrielle", "gabriel"},
+		{"bastille", "bastiel"},
+	})
+
+	// silent '-LL-'
+	testSoundsAlike(t, [][2]string{
+		{"marseille", "marsay"},
+		{"marseilles", "marsay"},
+		{"versailles", "versai"},
+		{"mireille", "miray"},
+	})
+}
+
+func TestLowercaseOutput(t *testing.T) {
+	for _, e := range allEncoders() {
+		lower := &Encoder{EncodeVowels: e.EncodeVowels, EncodeExact: e.EncodeExact, LowercaseOutput: true}
+
+		for _, in := range []string{"Smith", "Thompson", "Schmidt", "Xavier", "Catherine"} {
+			wantPrim, wantSecond := e.Encode(in)
+			prim, second := lower.Encode(in)
+			if prim != strings.ToLower(wantPrim) || second != strings.ToLower(wantSecond) {
+				t.Errorf("%v: expected %v, %v got %v, %v", in, strings.ToLower(wantPrim), strings.ToLower(wantSecond), prim, second)
+			}
+		}
+	}
+
+	e := &Encoder{LowercaseOutput: true}
+	if prim, second := e.Encode("Smith"); prim != "sm0" || second != "xmt" {
+		t.Errorf("Expected sm0, xmt got %v, %v", prim, second)
+	}
+}
+
+func TestInterjections(t *testing.T) {
+	testSoundsAlike(t, [][2]string{
+		{"hmm", "hmmm"},
+		{"hmm", "hm"},
+		{"brr", "brrr"},
+		{"psst", "pst"},
+		{"shh", "shhh"},
+		{"shh", "sh"},
+		{"mm", "mmm"},
+		{"zz", "zzz"},
+		{"h", "hh"},
+	})
+
+	for _, e := range allEncoders() {
+		for _, in := range []string{"hmm", "brr", "psst", "tsk", "shh", "h"} {
+			if prim, _ := e.Encode(in); prim == "" {
+				t.Errorf("Expected '%v' to have a metaphone with vowels=%v exact=%v", in, e.EncodeVowels, e.EncodeExact)
+			}
+		}
+	}
+
+	// only a drawn out letter is collapsed, other runs of consonants keep their sounds
+	e := &Encoder{}
+	for _, tc := range [][2]string{{"mrs", "MSS"}, {"gbp", "KPP"}, {"cx", "KKS"}, {"xsl", "SSL"}} {
+		if prim, _ := e.Encode(tc[0]); prim != tc[1] {
+			t.Errorf("Expected '%v' to encode as %v, got %v", tc[0], tc[1], prim)
+		}
+	}
+}
+
+func TestFinalGlides(t *testing.T) {
+	testSoundsAlike(t, [][2]string{
+		{"new", "noo"},
+		{"knew", "nu"},
+		{"flow", "flo"},
+		{"cow", "cau"},
+		{"law", "la"},
+		{"saw", "sa"},
+		{"stew", "stu"},
+		{"drew", "dru"},
+		{"andrew", "andru"},
+		{"hew", "hue"},
+		{"hew", "Hue"},
+		{"how", "hau"},
+		{"haw", "ha"},
+		{"crow", "crowe"},
+		{"low", "lowe"},
+	})
+}
+
+func TestItalianGl(t *testing.T) {
+	testSoundsAlike(t, [][2]string{
+		{"modigliani", "modiliani"},
+		{"tagliatelle", "taliatelle"},
+		{"intaglio", "intalio"},
+		{"puglia", "pulia"},
+		{"castiglione", "castilione"},
+		{"battaglia", "batalia"},
+		{"gigli", "jili"},
+	})
+
+	// english "GL" keeps the 'G'
+	e := &Encoder{}
+	for _, in := range []string{"glad", "uglier", "ugliest", "ogling"} {
+		if prim, _ := e.Encode(in); !strings.Contains(prim, "KL") {
+			t.Errorf("Expected '%v' to keep KL, got %v", in, prim)
+		}
+	}
+}
+
+func TestStripNameSuffixes(t *testing.T) {
+	vals := []struct {
+		in, want string
+	}{
+		{"John Smith III", "John Smith"},
+		{"John Smith, Jr.", "John Smith"},
+		{"John Smith Sr", "John Smith"},
+		{"Henry VIII", "Henry"},
+		{"John Smith Jr. PhD", "John Smith"},
+		{"Jane Smith, Ph.D.", "Jane Smith"},
+		{"John Smith Esq.", "John Smith"},
+		// a lone suffix is kept
+		{"III", "III"},
+		{"Jr", "Jr"},
+	}
+
+	e := &Encoder{StripNameSuffixes: true}
+	plain := &Encoder{}
+	for _, v := range vals {
+		wantPrim, wantSecond := plain.Encode(v.want)
+		if prim, second := e.Encode(v.in); prim != wantPrim || second != wantSecond {
+			t.Errorf("%v: expected %v, %v got %v, %v", v.in, wantPrim, wantSecond, prim, second)
+		}
+	}
+
+	// off by default
+	if prim, _ := plain.Encode("Henry VIII"); prim == "HNR" {
+		t.Errorf("Expected suffix to be kept by default")
+	}
+}
+
+func TestMacMc(t *testing.T) {
+	testSoundsAlike(t, [][2]string{
+		{"mcadam", "macadam"},
+		{"mcarthur", "macarthur"},
+		{"mcalister", "macalister"},
+		{"mcewen", "macewen"},
+		{"mcinnes", "macinnes"},
+		{"mcisaac", "macisaac"},
+		{"mceachern", "maceachern"},
+		{"mcintyre", "macintyre"},
+		{"mcevoy", "macevoy"},
+		{"macon", "makon"},
+		{"macy", "masey"},
+		{"macedonia", "masedonia"},
+	})
+}
+
+func TestJavaCompatTrim(t *testing.T) {
+	words := []string{"Villafranca", "Schwarzenegger", "internationalization", "pneumonoultramicroscopicsilicovolcanoconiosis"}
+
+	// the same as the default for any MaxLength the java implementation supports
+	for _, e := range allEncoders() {
+		for _, maxLen := range []int{0, 4, 8, 32} {
+			e.MaxLength = maxLen
+			java := &Encoder{EncodeVowels: e.EncodeVowels, EncodeExact: e.EncodeExact, MaxLength: maxLen, JavaCompatTrim: true}
+			for _, in := range words {
+				wantPrim, wantSecond := e.Encode(in)
+				if prim, second := java.Encode(in); prim != wantPrim || second != wantSecond {
+					t.Errorf("%v (max %v): expected %v, %v got %v, %v", in, maxLen, wantPrim, wantSecond, prim, second)
+				}
+			}
+		}
+	}
+
+	// java caps keys at MAX_KEY_ALLOCATION (32) in SetKeyLength, and runs its main loop
+	// until both keys are past the cap before trimming them
+	tests := []struct {
+		in, prim, second string
+	}{
+		{"pneumonoultramicroscopicsilicovolcanoconiosis", "NAMANALTRAMAKRASKAPAKSALAKAFALKA", ""},
+		{"supercalifragilisticexpialidocious", "SAPARKALAFRAJALASTASAKSPALATAXAS", "SAPARKALAFRAKALASTASAKSPALATASAS"},
+		{"Wolfeschlegelsteinhausenbergerdorff", "ALFAXLAJALSTANASANPARKARTARF", "FALFAXLAKALSTANASANPARKARTARF"},
+	}
+	java := &Encoder{EncodeVowels: true, MaxLength: 40, JavaCompatTrim: true}
+	for _, tc := range tests {
+		if prim, second := java.Encode(tc.in); prim != tc.prim || second != tc.second {
+			t.Errorf("%v: expected %v, %v got %v, %v", tc.in, tc.prim, tc.second, prim, second)
+		}
+	}
+
+	// without it the key can be longer than 32
+	e := &Encoder{EncodeVowels: true, MaxLength: 40}
+	if prim, _ := e.Encode(tests[0].in); len(prim) <= 32 {
+		t.Errorf("Expected more than 32 runes without JavaCompatTrim, got %v", prim)
+	}
+}
+
+func TestScottishCh(t *testing.T) {
+	// 'K' for americans with an 'H' alternate
+	testSoundsAlike(t, [][2]string{
+		{"brechin", "brehin"},
+		{"loch", "lock"},
+		{"lachlan", "laklan"},
+		{"mclachlan", "mcLaklan"},
+		{"buchanan", "bukanan"},
+		{"murdoch", "murdock"},
+		{"tulloch", "tullock"},
+		{"docherty", "dockerty"},
+		{"auchinleck", "akinleck"},
+		{"brechin", "breckin"},
+		{"ochiltree", "ockiltree"},
+	})
+
+	// silent
+	testSoundsAlike(t, [][2]string{
+		{"strachan", "strawn"},
+		{"crichton", "criton"},
+	})
+}
+
+func TestEqualKeys(t *testing.T) {
+	vals := []struct {
+		a, b string
+		want bool
+	}{
+		{"SM0", "SM0", true},
+		{"SM0", "sm0", true},
+		{"sm0", "Sm0", true},
+		{"XMT", "xmt", true},
+		{"SM0", "XMT", false},
+		{"SM0", "sm", false},
+		{"", "", true},
+	}
+
+	for _, v := range vals {
+		if got := EqualKeys(v.a, v.b); got != v.want {
+			t.Errorf("EqualKeys('%v', '%v') wanted %v, got %v", v.a, v.b, v.want, got)
+		}
+	}
+
+	upper := &Encoder{}
+	lower := &Encoder{LowercaseOutput: true}
+	for _, in := range []string{"Smith", "Thompson", "Catherine"} {
+		p1, s1 := upper.Encode(in)
+		p2, s2 := lower.Encode(in)
+		if !EqualKeys(p1, p2) || !EqualKeys(s1, s2) {
+			t.Errorf("%v: expected %v, %v to equal %v, %v", in, p1, s1, p2, s2)
+		}
+	}
+}
+
+func TestAlkAll(t *testing.T) {
+	// silent 'L'
+	testSoundsAlike(t, [][2]string{
+		{"walk", "wok"},
+		{"talk", "tock"},
+		{"stalk", "stock"},
+		{"chalk", "chock"},
+		{"balk", "bock"},
+		{"folk", "foke"},
+		{"polk", "poke"},
+	})
+
+	// 'L' is kept
+	testSoundsAlike(t, [][2]string{
+		{"ball", "bawl"},
+		{"tall", "tawl"},
+		{"hall", "haul"},
+		{"call", "caul"},
+	})
+
+	e := &Encoder{}
+	for _, in := range []string{"ball", "tall", "falkland", "balcony", "talcum", "alkali"} {
+		if prim, _ := e.Encode(in); !strings.Contains(prim, "L") {
+			t.Errorf("Expected '%v' to keep L, got %v", in, prim)
+		}
+	}
+}
+
+func TestMptMn(t *testing.T) {
+	testSoundsAlike(t, [][2]string{
+		// 'P' kept with an alternate without it
+		{"empty", "emty"},
+		{"tempt", "temt"},
+		{"prompt", "promt"},
+		{"attempt", "attemt"},
+		{"assumption", "assumtion"},
+		// silent 'P'
+		{"comptroller", "controller"},
+		{"asymptote", "asymtote"},
+		// silent 'N'
+		{"hymn", "him"},
+		{"autumn", "autum"},
+		{"column", "colum"},
+		{"damn", "dam"},
+		{"solemn", "solem"},
+	})
+
+	// 'N' is pronounced when a suffix follows
+	e := &Encoder{}
+	for _, in := range []string{"hymnal", "autumnal", "damnation"} {
+		if prim, _ := e.Encode(in); !strings.Contains(prim, "MN") {
+			t.Errorf("Expected '%v' to keep MN, got %v", in, prim)
+		}
+	}
+}
+
+func TestNgthNgue(t *testing.T) {
+	testSoundsAlike(t, [][2]string{
+		{"strength", "strenth"},
+		{"length", "lenth"},
+		{"tongue", "tong"},
+		{"tongue", "tung"},
+		{"tongues", "tongs"},
+		{"tongues", "tungs"},
+		{"harangue", "harang"},
+		{"meringue", "merang"},
+		{"language", "langwidge"},
+		{"penguin", "pengwin"},
+		{"anguish", "angwish"},
+		{"linguist", "lingwist"},
+		{"distinguish", "distingwish"},
+		{"plagues", "plags"},
+		{"leagues", "leegs"},
+		{"plagued", "plagd"},
+	})
+
+	table := []struct {
+		in, prim, vowels string
+	}{
+		// the 'G' isn't pronounced
+		{"strength", "STRN0", "STRAN0"},
+		{"length", "LN0", "LAN0"},
+		// spanish and portuguese names keep their 'E'
+		{"Vasques", "FSKS", "VASKAS"},
+		{"Rodrigues", "RTRKS", "RADRAGAS"},
+		{"Yagues", "AKS", "AGAS"},
+	}
+	e := &Encoder{}
+	ve := &Encoder{EncodeVowels: true, EncodeExact: true}
+	for _, tc := range table {
+		if prim, _ := e.Encode(tc.in); prim != tc.prim {
+			t.Errorf("%v: expected %v, got %v", tc.in, tc.prim, prim)
+		}
+		if prim, _ := ve.Encode(tc.in); prim != tc.vowels {
+			t.Errorf("%v: expected %v with vowels, got %v", tc.in, tc.vowels, prim)
+		}
+	}
+}
+
+func TestPreserveVowelRuns(t *testing.T) {
+	table := []struct {
+		in, def, preserved string
+	}{
+		{"higher", "HAR", "HAAR"},
+		{"highway", "HA", "HAA"},
+		{"debuted", "TAPAT", "TAPAAT"},
+		// adjacent vowels are still one 'A'
+		{"noisy", "NASA", "NASA"},
+		{"smith", "SMA0", "SMA0"},
+	}
+
+	def := &Encoder{EncodeVowels: true}
+	preserved := &Encoder{EncodeVowels: true, PreserveVowelRuns: true}
+	for _, test := range table {
+		if prim, _ := def.Encode(test.in); prim != test.def {
+			t.Errorf("Expected '%v' to be %v, got %v", test.in, test.def, prim)
+		}
+		if prim, _ := preserved.Encode(test.in); prim != test.preserved {
+			t.Errorf("Expected '%v' to be %v with PreserveVowelRuns, got %v", test.in, test.preserved, prim)
+		}
+	}
+
+	// no effect without vowels
+	e := &Encoder{PreserveVowelRuns: true}
+	if prim, _ := e.Encode("higher"); prim != "HR" {
+		t.Errorf("Expected 'higher' to be HR without EncodeVowels, got %v", prim)
+	}
+}
+
+func TestIgn(t *testing.T) {
+	// silent 'G'
+	testSoundsAlike(t, [][2]string{
+		{"sign", "sine"},
+		{"reign", "rain"},
+		{"design", "desine"},
+		{"campaign", "campane"},
+		{"foreign", "foren"},
+		{"feign", "fain"},
+		{"benign", "benine"},
+		{"designer", "desiner"},
+	})
+
+	// pronounced 'G'
+	e := &Encoder{}
+	for _, in := range []string{"signal", "signature", "resignation", "benignant", "ignite"} {
+		if prim, _ := e.Encode(in); !strings.Contains(prim, "KN") {
+			t.Errorf("Expected '%v' to keep KN, got %v", in, prim)
+		}
+	}
+
+	// "-AIGN", "-EIGN", and "-OIGN"
+	table := []struct {
+		in, want string
+	}{
+		{"campaign", "KMPN"},
+		{"campaigns", "KMPNS"},
+		{"champagne", "XMPN"},
+		{"champaign", "XMPN"},
+		{"arraignment", "ARNMNT"},
+		{"sovereign", "SFRN"},
+		{"foreigner", "FRNR"},
+		{"feigned", "FNT"},
+		{"poignant", "PNNT"},
+		{"poignancy", "PNNTS"},
+	}
+
+	for _, test := range table {
+		if prim, _ := e.Encode(test.in); prim != test.want {
+			t.Errorf("Expected '%v' to be %v, got %v", test.in, test.want, prim)
+		}
+	}
+
+	testSoundsAlike(t, [][2]string{
+		{"campaign", "campane"},
+		{"champagne", "champaign"},
+		{"reign", "rane"},
+		{"poignant", "poinyant"},
+	})
+}
+
+func TestGermanDt(t *testing.T) {
+	testSoundsAlike(t, [][2]string{
+		{"schmidt", "smith"},
+		{"schmidt", "schmitt"},
+		{"brandt", "brant"},
+		{"arendt", "arent"},
+		{"berndt", "bernt"},
+		{"wendt", "went"},
+		{"feldt", "felt"},
+		{"reinhardt", "reinhart"},
+		{"gerhardt", "gerhart"},
+		{"eckhardt", "eckhart"},
+		{"schmidt", "shmit"},
+		{"arndt", "arnt"},
+		{"bernhardt", "bernhart"},
+	})
+
+	// "-DT" is a single 'T'
+	for _, e := range allEncoders() {
+		for _, in := range []string{"schmidt", "brandt", "arendt", "arndt", "mindt"} {
+			if prim, _ := e.Encode(in); strings.Count(prim, "T")+strings.Count(prim, "D") != 1 {
+				t.Errorf("Expected a single T in '%v', got %v with vowels=%v exact=%v", in, prim, e.EncodeVowels, e.EncodeExact)
+			}
+		}
+	}
+}
+
+func TestSpanishEz(t *testing.T) {
+	testSoundsAlike(t, [][2]string{
+		{"rodriguez", "rodrigues"},
+		{"gonzalez", "gonzales"},
+		{"hernandez", "hernandes"},
+		{"lopez", "lopes"},
+		{"chavez", "chaves"},
+		{"perez", "peres"},
+	})
+
+	// the final Z is not silent like french "chez" and "rendez"
+	for _, e := range allEncoders() {
+		for _, in := range []string{"lopez", "perez", "gomez", "ramirez", "martinez", "diaz"} {
+			if prim, _ := e.Encode(in); !strings.HasSuffix(prim, "S") {
+				t.Errorf("Expected '%v' to end in S, got %v with vowels=%v exact=%v", in, prim, e.EncodeVowels, e.EncodeExact)
+			}
+		}
+	}
+}
+
+func TestXc(t *testing.T) {
+	testSoundsAlike(t, [][2]string{
+		{"excellent", "exsellent"},
+		{"excel", "eksel"},
+		{"except", "eksept"},
+		{"exceed", "ekseed"},
+		{"excite", "eksite"},
+	})
+
+	// "XC" before a hard vowel or consonant keeps the 'K'
+	e := &Encoder{}
+	for _, in := range []string{"excuse", "exclaim", "excalibur"} {
+		if prim, _ := e.Encode(in); !strings.HasPrefix(prim, "AKSK") {
+			t.Errorf("Expected '%v' to start with AKSK, got %v", in, prim)
+		}
+	}
+
+	// the soft 'C' before a front vowel is part of the "KS"
+	vals := []struct {
+		word, want string
+	}{
+		{"excel", "AKSL"},
+		{"except", "AKSPT"},
+		{"excite", "AKST"},
+		{"excise", "AKSS"},
+		{"exceed", "AKST"},
+		{"excyst", "AKSST"},
+		{"excavate", "AKSKFT"},
+	}
+	for _, v := range vals {
+		if prim, _ := e.Encode(v.word); prim != v.want {
+			t.Errorf("Expected '%v' to be %v, got %v", v.word, v.want, prim)
+		}
+	}
+}
+
+func TestInternalWr(t *testing.T) {
+	testSoundsAlike(t, [][2]string{
+		{"playwright", "playrite"},
+		{"shipwreck", "shiprek"},
+		{"cartwright", "cartrite"},
+		{"unwrap", "unrap"},
+		{"rewrite", "rerite"},
+		{"awry", "aray"},
+	})
+}
+
+func TestIsLikelyKey(t *testing.T) {
+	vals := []struct {
+		in   string
+		want bool
+	}{
+		{"SM0", true},
+		{"sm0", true},
+		{"XMT", true},
+		{"ANKLX", true},
+		{"Sm0", false},
+		{"Smith", false},
+		{"SMITH", false},
+		{"hello", false},
+		{"SM 0", false},
+		{"", false},
+	}
+
+	for _, v := range vals {
+		if got := IsLikelyKey(v.in); got != v.want {
+			t.Errorf("IsLikelyKey('%v') wanted %v, got %v", v.in, v.want, got)
+		}
+	}
+
+	// every key we make should look like a key
+	for _, e := range allEncoders() {
+		for _, in := range []string{"Smith", "Thompson", "Catherine", "Schmidt", "Xavier", "Gough"} {
+			prim, second := e.Encode(in)
+			if !IsLikelyKey(prim) || (second != "" && !IsLikelyKey(second)) {
+				t.Errorf("Expected keys of '%v' to look like keys, got %v %v", in, prim, second)
+			}
+		}
+	}
+}
+
+func TestSpellAcronyms(t *testing.T) {
+	table := []struct {
+		in, want string
+	}{
+		{"FBI", "AFP"},
+		{"HTML", "AXTML"},
+		{"IBM", "APM"},
+		{"NBC", "ANPS"},
+		// more likely words
+		{"NASA", "NS"},
+		{"COX", "KKS"},
+		{"NO", "N"},
+		{"IT", "AT"},
+		{"JOE", "J"},
+		{"TO", "T"},
+		{"ANN", "AN"},
+		{"CONSTRUCTION", "KNSTRKXN"},
+		// not all capitals
+		{"Fbi", "FP"},
+		{"html", "TML"},
+	}
+
+	e := &Encoder{SpellAcronyms: true, MaxLength: 20}
+	for _, test := range table {
+		if prim, _ := e.Encode(test.in); prim != test.want {
+			t.Errorf("Expected '%v' to be %v, got %v", test.in, test.want, prim)
+		}
+	}
+
+	// default off
+	e = &Encoder{}
+	if prim, _ := e.Encode("FBI"); prim != "FP" {
+		t.Errorf("Expected 'FBI' to be FP without SpellAcronyms, got %v", prim)
+	}
+}
+
+func TestMinLength(t *testing.T) {
+	table := []struct {
+		in                string
+		min, max          int
+		pad               rune
+		wantPrim, wantSec string
+	}{
+		// shorter than MinLength
+		{"a", 4, 0, '0', "A000", ""},
+		{"smith", 4, 0, '0', "SM00", "XMT0"},
+		{"smith", 6, 0, 0, "SM0   ", "XMT   "},
+		// exactly MinLength
+		{"stern", 4, 0, '0', "STRN", ""},
+		// longer than MinLength
+		{"construction", 4, 0, '0', "KNSTRKXN", ""},
+		// never past MaxLength
+		{"a", 8, 3, '_', "A__", ""},
+		{"smith", 20, 0, '_', "SM0_____", "XMT_____"},
+		// no padding
+		{"a", 0, 0, '0', "A", ""},
+	}
+
+	for _, test := range table {
+		e := &Encoder{MinLength: test.min, MaxLength: test.max, PadChar: test.pad}
+		prim, sec := e.Encode(test.in)
+		if prim != test.wantPrim || sec != test.wantSec {
+			t.Errorf("Expected '%v' with MinLength %v to be %q %q, got %q %q", test.in, test.min, test.wantPrim, test.wantSec, prim, sec)
+		}
+	}
+
+	e := &Encoder{MinLength: 4}
+	if prim, sec := e.Encode(""); prim != "" || sec != "" {
+		t.Errorf("Expected blank input to have blank metaphones, got %q %q", prim, sec)
+	}
+}
+
+func TestOugh(t *testing.T) {
+	table := []struct {
+		in, want string
+	}{
+		// silent
+		{"through", "0R"},
+		{"breakthrough", "PRK0R"},
+		{"although", "AL0"},
+		{"thorough", "0R"},
+		{"borough", "PR"},
+		{"plough", "PL"},
+		{"doughnut", "TNT"},
+		// 'F'
+		{"roughly", "RFL"},
+		{"enough", "ANF"},
+		{"coughing", "KFNK"},
+		{"chough", "XF"},
+		// special cases
+		{"hiccough", "HKP"},
+		{"lough", "LK"},
+		{"thought", "0T"},
+	}
+
+	e := &Encoder{}
+	for _, test := range table {
+		if prim, _ := e.Encode(test.in); prim != test.want {
+			t.Errorf("Expected '%v' to be %v, got %v", test.in, test.want, prim)
+		}
+	}
+
+	testSoundsAlike(t, [][2]string{
+		{"hiccough", "hiccup"},
+		{"plough", "plow"},
+		{"though", "tho"},
+		{"through", "thru"},
+		{"rough", "ruff"},
+		{"hough", "huff"},
+		{"lough", "loch"},
+	})
+
+	// british "-BOROUGH" and "-BURGH" have a schwa ending
+	testSoundsAlike(t, [][2]string{
+		{"borough", "burra"},
+		{"thorough", "thurra"},
+		{"scarborough", "scarbura"},
+		{"edinburgh", "edinburra"},
+		{"edinburgh", "edinboro"},
+		{"roxburgh", "roxburra"},
+	})
+
+	// the american 'G' is still the primary
+	if prim, sec := e.Encode("pittsburgh"); prim != "PTSPRK" || sec != "PTSPR" {
+		t.Errorf("Expected 'pittsburgh' to be PTSPRK PTSPR, got %v %v", prim, sec)
+	}
+}
+
+// Noun/verb homographs (e.g. "REcord" vs "reCORD") only differ by stress, which
+// metaphone3 ignores, so they always share a key.
+func TestHomographs(t *testing.T) {
+	homographs := []string{"record", "present", "produce", "object", "project", "permit",
+		"conduct", "contract", "convert", "desert", "import", "export", "increase", "insult",
+		"progress", "protest", "rebel", "refuse", "subject", "suspect"}
+
+	// re-using an encoder can't change the key
+	for _, e := range allEncoders() {
+		for _, in := range homographs {
+			p1, s1 := e.Encode(in)
+			e.Encode("construction")
+			p2, s2 := e.Encode(strings.ToUpper(in))
+			if p1 != p2 || s1 != s2 {
+				t.Errorf("Expected '%v' to have the same key, got %v %v and %v %v", in, p1, s1, p2, s2)
+			}
+		}
+	}
+}
+
+func TestFrenchCh(t *testing.T) {
+	table := []struct {
+		in, want string
+	}{
+		{"machine", "MXN"},
+		{"chef", "XF"},
+		{"chic", "XK"},
+		{"chute", "XT"},
+		{"chandelier", "XNTLR"},
+		{"chaperone", "XPRN"},
+		{"quiche", "KX"},
+		{"cliche", "KLX"},
+		{"pastiche", "PSTX"},
+		{"crochet", "KRX"},
+		{"brochure", "PRXR"},
+		{"michelle", "MXL"},
+		{"caches", "KXS"},
+		{"caching", "KXNK"},
+	}
+
+	// french 'CH' is only 'X'
+	e := &Encoder{}
+	for _, test := range table {
+		if prim, sec := e.Encode(test.in); prim != test.want || sec != "" {
+			t.Errorf("Expected '%v' to be %v, got %v %v", test.in, test.want, prim, sec)
+		}
+	}
+
+	// greek 'CH' is still 'K'
+	for _, in := range []string{"chorus", "chemistry", "spirochete", "cachexia"} {
+		if prim, _ := e.Encode(in); !strings.Contains(prim, "K") {
+			t.Errorf("Expected '%v' to have a K, got %v", in, prim)
+		}
+	}
+
+	// other words and names keep the 'K' alternate, e.g. italian 'michele'
+	for _, in := range []string{"michele", "cachero", "schochet"} {
+		if _, sec := e.Encode(in); !strings.Contains(sec, "K") {
+			t.Errorf("Expected '%v' to have a K alternate, got %v", in, sec)
+		}
+	}
+
+	testSoundsAlike(t, [][2]string{
+		{"machine", "masheen"},
+		{"quiche", "keesh"},
+		{"niche", "neesh"},
+		{"mustache", "mustash"},
+		{"brochure", "broshure"},
+	})
+}
+
+func TestTch(t *testing.T) {
+	table := []struct {
+		in, want string
+	}{
+		// word-final
+		{"watch", "AX"},
+		{"scratch", "SKRX"},
+		{"hutch", "HX"},
+		// medial
+		{"kitchen", "KXN"},
+		{"butcher", "PXR"},
+		{"pitchfork", "PXFRK"},
+		// suffixed
+		{"catching", "KXNK"},
+		{"watches", "AXS"},
+		{"watched", "AXT"},
+		{"sketchy", "SKX"},
+		// names
+		{"ritchie", "RX"},
+		{"mitchell", "MXL"},
+	}
+
+	e := &Encoder{}
+	for _, test := range table {
+		if prim, _ := e.Encode(test.in); prim != test.want {
+			t.Errorf("Expected '%v' to be %v, got %v", test.in, test.want, prim)
+		}
+	}
+
+	testSoundsAlike(t, [][2]string{
+		{"kitchen", "kichen"},
+		{"butcher", "bucher"},
+		{"ritchie", "richie"},
+		{"mitchell", "michel"},
+	})
+}
+
+func TestGgle(t *testing.T) {
+	testSoundsAlike(t, [][2]string{
+		{"egg", "eg"},
+		{"bigger", "biger"},
+		{"gregg", "greg"},
+		{"struggle", "strugle"},
+		{"juggle", "jugle"},
+		{"goggles", "gogles"},
+	})
+
+	// "-GG-" is a single 'G', and "-GLE" gets the 'A' before the 'L'
+	e := &Encoder{EncodeVowels: true, EncodeExact: true}
+	for in, want := range map[string]string{"egg": "AG", "bigger": "BAGAR", "struggle": "STRAGAL", "haggle": "HAGAL"} {
+		if prim, _ := e.Encode(in); prim != want {
+			t.Errorf("Expected '%v' to be %v, got %v", in, want, prim)
+		}
+	}
+}
+
+func TestGgSplit(t *testing.T) {
+	vals := []struct {
+		word, want string
+	}{
+		// hard then soft
+		{"suggest", "SKJST"},
+		{"suggestion", "SKJSXN"},
+		// one soft 'G'
+		{"exaggerate", "AKSJRT"},
+		{"reggie", "RJ"},
+		{"veggie", "FJ"},
+		{"loggia", "LJ"},
+		// one hard 'G'
+		{"bigger", "PKR"},
+		{"nugget", "NKT"},
+	}
+
+	e := &Encoder{}
+	for _, v := range vals {
+		if prim, _ := e.Encode(v.word); prim != v.want {
+			t.Errorf("Expected '%v' to be %v, got %v", v.word, v.want, prim)
+		}
+	}
+}
+
+func TestClitics(t *testing.T) {
+	table := []struct {
+		in, want string
+	}{
+		// possessives
+		{"John's", "JN"},
+		{"dog's", "TK"},
+		{"dogs'", "TKS"},
+		{"James's", "JMS"},
+		{"James'", "JMS"},
+		{"Smith’s", "SM0"},
+		// contractions
+		{"it's", "ATS"},
+		{"that's", "0TS"},
+		{"'twas", "TS"},
+		{"'em", "AM"},
+		{"'tis", "TS"},
+		// apostrophes inside names are unchanged
+		{"O'Brien", "APRN"},
+		{"'", ""},
+	}
+
+	e := &Encoder{}
+	for _, test := range table {
+		if prim, _ := e.Encode(test.in); prim != test.want {
+			t.Errorf("Expected '%v' to be %v, got %v", test.in, test.want, prim)
+		}
+	}
+
+	testSoundsAlike(t, [][2]string{
+		{"John's", "John"},
+		{"dog's", "dog"},
+		{"dogs'", "dogs"},
+		{"'em", "em"},
+		{"it's", "its"},
+		{"let's", "lets"},
+	})
+}
+
+func TestEsPossessives(t *testing.T) {
+	// the pronounced 'E' in spanish and portuguese surnames is kept in the
+	// possessive, e.g. "torres's" is "TARAS" not "TARS"
+	e := &Encoder{EncodeVowels: true}
+	for _, in := range []string{"torres", "flores", "gonzales", "morales", "lopes", "cortes", "jose", "charles", "jones", "mendes"} {
+		p1, s1 := e.Encode(in)
+		p2, s2 := e.Encode(in + "'s")
+		if p1 != p2 || s1 != s2 {
+			t.Errorf("Expected '%v' and its possessive to match, got %v %v and %v %v", in, p1, s1, p2, s2)
+		}
+	}
+
+	table := []struct {
+		in, want string
+	}{
+		{"torres", "TARAS"},
+		{"torreses", "TARASAS"},
+		{"flores", "FLARAS"},
+		{"jose", "HASA"},
+		// silent 'E'
+		{"charles", "XARLS"},
+		{"mendes", "MANTS"},
+		// the extra plural is pronounced
+		{"charleses", "XARLASAS"},
+		{"joneses", "JANASAS"},
+	}
+
+	for _, test := range table {
+		if prim, _ := e.Encode(test.in); prim != test.want {
+			t.Errorf("Expected '%v' to be %v, got %v", test.in, test.want, prim)
+		}
+	}
+}
+
+func TestCorpusInvariants(t *testing.T) {
+	files, err := filepath.Glob(filepath.Join("testdata", "*.test"))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for _, file := range files {
+		csvFile, err := os.Open(file)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		lines, err := csv.NewReader(csvFile).ReadAll()
+		csvFile.Close()
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		for _, e := range allEncoders() {
+			// like the reference only a lone 'A' is deduped, so with vowels a string
+			// like the "AR" in "myhre" can follow another 'A'
+			for _, line := range lines {
+				prim, sec := e.Encode(line[0])
+				if err := checkKey(prim, e.MaxLength, e.EncodeVowels); err != nil {
+					t.Errorf("%v: primary %v of '%v' with vowels=%v exact=%v: %v", file, prim, line[0], e.EncodeVowels, e.EncodeExact, err)
+				}
+				if err := checkKey(sec, e.MaxLength, e.EncodeVowels); err != nil {
+					t.Errorf("%v: secondary %v of '%v' with vowels=%v exact=%v: %v", file, sec, line[0], e.EncodeVowels, e.EncodeExact, err)
+				}
+			}
+		}
+	}
+}
+
+func TestEi(t *testing.T) {
+	testSoundsAlike(t, [][2]string{
+		// long 'E'
+		{"receive", "receeve"},
+		{"deceive", "deceeve"},
+		{"ceiling", "seeling"},
+		{"seize", "seeze"},
+		// long 'A' with a silent "GH"
+		{"eight", "ate"},
+		{"weight", "wait"},
+		{"freight", "frate"},
+		{"sleigh", "slay"},
+		{"neighbor", "nayber"},
+		{"height", "hite"},
+		// long 'A' with a silent 'G'
+		{"reign", "rain"},
+		{"vein", "vane"},
+	})
+}
+
+func TestDoubledConsonantNames(t *testing.T) {
+	testSoundsAlike(t, [][2]string{
+		{"bobby", "bobbie"},
+		{"emmett", "emmitt"},
+		{"emmett", "emmet"},
+		{"terrell", "terrel"},
+		{"jimmy", "jimmie"},
+		{"tammy", "tami"},
+		{"kelly", "kellie"},
+		{"sally", "sallie"},
+		{"dennis", "denis"},
+		{"lynne", "lyn"},
+		{"connor", "conor"},
+		{"matthew", "mathew"},
+		{"phillippe", "philippe"},
+		{"anna", "ana"},
+		{"allan", "alan"},
+	})
+}
+
+func TestSteinBerg(t *testing.T) {
+	table := []struct {
+		in, want string
+	}{
+		{"einstein", "ANSTN"},
+		{"rothstein", "R0STN"},
+		{"bernstein", "PRNSTN"},
+		{"goldberg", "KLTPRK"},
+		{"spielberg", "SPLPRK"},
+		{"steinberg", "STNPRK"},
+	}
+
+	e := &Encoder{}
+	for _, test := range table {
+		if prim, _ := e.Encode(test.in); prim != test.want {
+			t.Errorf("Expected '%v' to be %v, got %v", test.in, test.want, prim)
+		}
+	}
+
+	// the final 'G' is hard
+	e = &Encoder{EncodeExact: true}
+	for _, in := range []string{"goldberg", "greenberg", "bloomberg", "eisenberg"} {
+		if prim, _ := e.Encode(in); !strings.HasSuffix(prim, "RG") {
+			t.Errorf("Expected '%v' to end in RG, got %v", in, prim)
+		}
+	}
+
+	testSoundsAlike(t, [][2]string{
+		{"einstein", "einsteen"},
+		{"bernstein", "bernsteen"},
+		{"goldberg", "goldburg"},
+		{"greenberg", "greenburg"},
+	})
+}
+
+func TestEaux(t *testing.T) {
+	testSoundsAlike(t, [][2]string{
+		{"bureau", "bureaux"},
+		{"plateau", "plateaux"},
+		{"tableau", "tableaux"},
+		{"chateau", "chateaux"},
+		{"gateau", "gateaux"},
+		{"beau", "beaux"},
+		{"trousseau", "trousseaux"},
+		{"bordeaux", "bordo"},
+		{"breaux", "bro"},
+	})
+
+	// the final 'X' is silent
+	for _, e := range allEncoders() {
+		for _, in := range []string{"bureaux", "plateaux", "gateaux", "thibodeaux"} {
+			if prim, _ := e.Encode(in); strings.Contains(prim, "KS") {
+				t.Errorf("Expected '%v' to have a silent X, got %v with vowels=%v exact=%v", in, prim, e.EncodeVowels, e.EncodeExact)
+			}
+		}
+	}
+}
+
+func TestEncodeReversed(t *testing.T) {
+	vals := []struct{ in, reversed string }{
+		{"Smith", "htimS"},
+		{"Thompson", "nospmohT"},
+		{"Muñoz", "zoñuM"},
+		{"Gößmann", "nnamßöG"},
+		{"a", "a"},
+		{"", ""},
+	}
+
+	for _, e := range allEncoders() {
+		for _, v := range vals {
+			p1, s1 := e.EncodeReversed(v.in)
+			p2, s2 := e.Encode(v.reversed)
+			if p1 != p2 || s1 != s2 {
+				t.Errorf("Expected '%v' reversed to be %v %v, got %v %v", v.in, p2, s2, p1, s1)
+			}
+		}
+	}
+}
+
+func TestFinalPs(t *testing.T) {
+	testSoundsAlike(t, [][2]string{
+		// french silent "PS"
+		{"corps", "core"},
+		{"corpsman", "corman"},
+		// pronounced "PS"
+		{"biceps", "biseps"},
+		{"forceps", "forseps"},
+		{"corpse", "korps"},
+	})
+
+	for _, e := range allEncoders() {
+		for _, in := range []string{"biceps", "triceps", "forceps", "corpse"} {
+			if prim, _ := e.Encode(in); !strings.HasSuffix(prim, "PS") {
+				t.Errorf("Expected '%v' to end in PS, got %v with vowels=%v exact=%v", in, prim, e.EncodeVowels, e.EncodeExact)
+			}
+		}
+	}
+}
+
+func TestInitialVowelDigraphs(t *testing.T) {
+	testSoundsAlike(t, [][2]string{
+		{"ian", "ean"},
+		{"ian", "iain"},
+		{"ieuan", "ewan"},
+		{"euan", "ewan"},
+		{"eileen", "aileen"},
+		{"eileen", "ilene"},
+		{"eamon", "aimon"},
+		{"aiden", "eden"},
+	})
+
+	// the initial digraph is a single 'A'
+	e := &Encoder{EncodeVowels: true}
+	for in, want := range map[string]string{"ian": "AN", "ieuan": "AN", "eileen": "ALAN", "eamon": "AMAN", "aiden": "ATAN"} {
+		if prim, _ := e.Encode(in); prim != want {
+			t.Errorf("Expected '%v' to be %v, got %v", in, want, prim)
+		}
+	}
+}
+
+func TestFinalOw(t *testing.T) {
+	table := []struct {
+		in, want string
+	}{
+		// the final 'W' is a vowel
+		{"meadow", "MT"},
+		{"borrow", "PR"},
+		{"yellow", "AL"},
+		{"cow", "K"},
+		{"now", "N"},
+		{"arnow", "ARN"},
+	}
+
+	e := &Encoder{}
+	for _, test := range table {
+		if prim, sec := e.Encode(test.in); prim != test.want || sec != "" {
+			t.Errorf("Expected '%v' to be %v, got %v %v", test.in, test.want, prim, sec)
+		}
+	}
+
+	// polish "-OWSKI" gets the 'F' alternate
+	for in, want := range map[string]string{"kozlowski": "KSLFSK", "pankowski": "PNKFSK", "tarnowski": "TRNFSK"} {
+		if _, sec := e.Encode(in); sec != want {
+			t.Errorf("Expected '%v' to have the alternate %v, got %v", in, want, sec)
+		}
+	}
+
+	testSoundsAlike(t, [][2]string{
+		{"meadow", "medo"},
+		{"yellow", "yello"},
+		{"borrow", "boro"},
+	})
+}
+
+func TestAughEighNames(t *testing.T) {
+	testSoundsAlike(t, [][2]string{
+		{"vaughn", "von"},
+		{"vaughan", "von"},
+		{"baugh", "bah"},
+		{"haughey", "hoey"},
+		{"leigh", "lee"},
+		{"raleigh", "rawley"},
+		{"burleigh", "burley"},
+		{"keogh", "keough"},
+	})
+
+	// the silent "GH" leaves a single 'A'
+	e := &Encoder{EncodeVowels: true}
+	for in, want := range map[string]string{"vaughn": "FAN", "baugh": "PA", "leigh": "LA", "raleigh": "RALA", "haughey": "HA"} {
+		if prim, _ := e.Encode(in); prim != want {
+			t.Errorf("Expected '%v' to be %v, got %v", in, want, prim)
+		}
+	}
+}
+
+func TestCc(t *testing.T) {
+	e := &Encoder{}
+	// front vowels get KS, back vowels and consonants get K, italian gets X
+	for word, want := range map[string]string{
+		"accept":     "AKSPT",
+		"succeed":    "SKST",
+		"vaccine":    "FKSN",
+		"occur":      "AKR",
+		"broccoli":   "PRKL",
+		"soccer":     "SKR",
+		"acclaim":    "AKLM",
+		"bacchus":    "PKS",
+		"flaccid":    "FLST",
+		"cappuccino": "KPXN",
+		"bocce":      "PX",
+		"recce":      "RK",
+	} {
+		if prim, _ := e.Encode(word); prim != want {
+			t.Errorf("Expected '%v' to be %v, got %v", word, want, prim)
+		}
+	}
+}
+
+func TestProfessionNationalityIan(t *testing.T) {
+	e := &Encoder{}
+	for _, v := range []struct{ word, prim, sec string }{
+		{"physician", "FSXN", "FSSN"},
+		{"musician", "MSXN", "MSSN"},
+		{"Egyptian", "AJPXN", "AKPTN"},
+		{"Martian", "MRXN", "MRTN"},
+		{"Christian", "KRSXN", "KRSTN"},
+		{"Russian", "RXN", ""},
+		{"Persian", "PRJN", ""},
+		{"Asian", "AJN", ""},
+		{"Parisian", "PRJN", "PRSN"},
+		{"Silesian", "SLJN", "SLSN"},
+	} {
+		if prim, sec := e.Encode(v.word); prim != v.prim || sec != v.sec {
+			t.Errorf("Expected '%v' to be %v %v, got %v %v", v.word, v.prim, v.sec, prim, sec)
+		}
+	}
+}
+
+func TestScotsOch(t *testing.T) {
+	// 'K' with an 'H' alternate for the scots "kh", unlike the 'X' alternate
+	// of the german 'ch' in e.g. 'bach'
+	e := &Encoder{}
+	for _, v := range []struct{ word, prim, sec string }{
+		{"loch", "LK", "LH"},
+		{"kinloch", "KNLK", "KNLH"},
+		{"mcculloch", "MKLK", "MKLH"},
+		{"McCulloch", "MKLK", "MKLH"},
+		{"murdoch", "MRTK", "MRTH"},
+		{"pibroch", "PPRK", "PPRX"},
+		{"bach", "PK", "PX"},
+	} {
+		if prim, sec := e.Encode(v.word); prim != v.prim || sec != v.sec {
+			t.Errorf("Expected '%v' to be %v %v, got %v %v", v.word, v.prim, v.sec, prim, sec)
+		}
+	}
+
+	testSoundsAlike(t, [][2]string{
+		{"balloch", "ballock"},
+		{"lochaber", "lockaber"},
+		{"buccleuch", "buckloo"},
+	})
+}
+
+func TestPronounceInitialH(t *testing.T) {
+	vals := []struct {
+		word           string
+		prim, sec      string
+		hPrim, hSecond string
+	}{
+		{"herb", "HRP", "ARP", "HRP", ""},
+		{"hour", "AR", "", "HR", ""},
+		{"honest", "ANST", "", "HNST", ""},
+		{"heir", "AR", "", "HR", ""},
+	}
+
+	e := &Encoder{}
+	h := &Encoder{PronounceInitialH: true}
+	for _, v := range vals {
+		if prim, sec := e.Encode(v.word); prim != v.prim || sec != v.sec {
+			t.Errorf("Expected '%v' to be %v %v, got %v %v", v.word, v.prim, v.sec, prim, sec)
+		}
+		if prim, sec := h.Encode(v.word); prim != v.hPrim || sec != v.hSecond {
+			t.Errorf("Expected '%v' with PronounceInitialH to be %v %v, got %v %v", v.word, v.hPrim, v.hSecond, prim, sec)
+		}
+	}
+}
+
+func TestValidSymbol(t *testing.T) {
+	for r := rune(0); r < 128; r++ {
+		want := strings.ContainsRune("0ABDFGHJKLMNPRSTVXabdfghjklmnprstvx", r)
+		if got := ValidSymbol(r); got != want {
+			t.Errorf("Expected ValidSymbol(%q) to be %v, got %v", r, want, got)
+		}
+	}
+	if ValidSymbol('Ö') {
+		t.Errorf("Expected ValidSymbol('Ö') to be false")
+	}
+}
+
+func TestMb(t *testing.T) {
+	vals := []struct {
+		word, want string
+	}{
+		// silent 'B'
+		{"lamb", "LM"},
+		{"comb", "KM"},
+		{"thumb", "0M"},
+		{"climber", "KLMR"},
+		{"climbing", "KLMNK"},
+		{"plumber", "PLMR"},
+		{"bomber", "PMR"},
+		{"tombs", "TMS"},
+		// pronounced 'B'
+		{"number", "NMPR"},
+		{"lumber", "LMPR"},
+		{"member", "MMPR"},
+		{"bombastic", "PMPSTK"},
+		{"limbo", "LMP"},
+	}
+
+	e := &Encoder{}
+	for _, v := range vals {
+		if prim, _ := e.Encode(v.word); prim != v.want {
+			t.Errorf("Expected '%v' to be %v, got %v", v.word, v.want, prim)
+		}
+	}
+}
+
+func TestFinalQue(t *testing.T) {
+	e := &Encoder{EncodeVowels: true}
+	vals := []struct {
+		word, want string
+	}{
+		// silent "-UE"
+		{"mosque", "MASK"},
+		{"opaque", "APAK"},
+		{"antique", "ANTAK"},
+		{"unique", "ANAK"},
+		{"boutique", "PATAK"},
+		{"grotesque", "KRATASK"},
+		// pronounced final 'E'
+		{"risque", "RASKA"},
+		{"enrique", "ANRAKA"},
+		{"barbeque", "PARPAKA"},
+		{"applique", "APLAKA"},
+		{"communique", "KAMANAKA"},
+		{"pirogue", "PARAKA"},
+	}
+
+	for _, v := range vals {
+		if prim, _ := e.Encode(v.word); prim != v.want {
+			t.Errorf("Expected '%v' to be %v, got %v", v.word, v.want, prim)
+		}
+	}
+}
+
+func TestDoubledNasals(t *testing.T) {
+	pairs := [][2]string{
+		{"connect", "conect"},
+		{"summer", "sumer"},
+		{"announce", "anounce"},
+		{"common", "comon"},
+		{"immense", "imense"},
+		{"annual", "anual"},
+		{"commit", "comit"},
+		{"innate", "inate"},
+		{"grammar", "gramar"},
+		{"tunnel", "tunel"},
+	}
+
+	// the redundant 'N' or 'M' is eaten so both encode identically
+	for _, e := range allEncoders() {
+		for _, p := range pairs {
+			p1, s1 := e.Encode(p[0])
+			p2, s2 := e.Encode(p[1])
+			if p1 != p2 || s1 != s2 {
+				t.Errorf("Expected '%v' (%v, %v) and '%v' (%v, %v) to be equal with vowels=%v exact=%v",
+					p[0], p1, s1, p[1], p2, s2, e.EncodeVowels, e.EncodeExact)
+			}
+		}
+	}
+}
+
+func TestMedicalSuffixes(t *testing.T) {
+	vals := []struct {
+		word, prim, sec string
+	}{
+		// "-OSIS"
+		{"diagnosis", "TKNSS", ""},
+		{"psychosis", "SKSS", "SXSS"},
+		{"tuberculosis", "TPRKLSS", ""},
+		// "-ITIS"
+		{"arthritis", "AR0RTS", ""},
+		{"bronchitis", "PRNKTS", "PRNXTS"},
+		{"meningitis", "MNNJTS", "MNNKTS"},
+		// "-OLOGY" has a soft 'G'
+		{"cardiology", "KRTLJ", ""},
+		{"psychology", "SKLJ", "SXLJ"},
+		{"geology", "JLJ", "KLJ"},
+		{"biologist", "PLJST", ""},
+		{"logic", "LJK", ""},
+	}
+
+	e := &Encoder{}
+	for _, v := range vals {
+		if prim, sec := e.Encode(v.word); prim != v.prim || sec != v.sec {
+			t.Errorf("Expected '%v' to be %v %v, got %v %v", v.word, v.prim, v.sec, prim, sec)
+		}
+	}
+}
+
+func TestSpellSymbols(t *testing.T) {
+	e := &Encoder{SpellSymbols: true}
+	pairs := [][2]string{
+		{"R&B", "R AND B"},
+		{"AT&T", "AT AND T"},
+		{"user@", "user at"},
+		{"50%", "50 percent"},
+		{"C+", "C plus"},
+	}
+	for _, p := range pairs {
+		p1, s1 := e.Encode(p[0])
+		p2, s2 := e.Encode(p[1])
+		if p1 != p2 || s1 != s2 {
+			t.Errorf("Expected '%v' (%v, %v) and '%v' (%v, %v) to be equal", p[0], p1, s1, p[1], p2, s2)
+		}
+	}
+
+	// symbols are dropped by default
+	d := &Encoder{}
+	if prim, _ := d.Encode("R&B"); prim != "RP" {
+		t.Errorf("Expected 'R&B' to be RP, got %v", prim)
+	}
+}
+
+func TestFrenchOir(t *testing.T) {
+	// the "OI" is a single vowel, the 'R' is kept, and a final 'E' is silent
+	e := &Encoder{EncodeVowels: true}
+	vals := []struct {
+		word, want string
+	}{
+		{"memoir", "MAMAR"},
+		{"reservoir", "RASARFAR"},
+		{"repertoire", "RAPARTAR"},
+		{"boudoir", "PATAR"},
+		{"Loire", "LAR"},
+		{"armoire", "ARMAR"},
+	}
+
+	for _, v := range vals {
+		if prim, _ := e.Encode(v.word); prim != v.want {
+			t.Errorf("Expected '%v' to be %v, got %v", v.word, v.want, prim)
+		}
+	}
+}
+
+func TestFrenchEur(t *testing.T) {
+	vals := []struct {
+		word, want string
+	}{
+		// the 'R' is pronounced
+		{"chauffeur", "XFR"},
+		{"entrepreneur", "ANTRPRNR"},
+		{"connoisseur", "KNSR"},
+		{"saboteur", "SPTR"},
+		{"liqueur", "LKR"},
+		{"masseuse", "MSS"},
+		{"chanteuse", "XNTS"},
+		// unlike "-IER"
+		{"monsieur", "MS"},
+		{"dossier", "TS"},
+	}
+
+	e := &Encoder{}
+	for _, v := range vals {
+		if prim, _ := e.Encode(v.word); prim != v.want {
+			t.Errorf("Expected '%v' to be %v, got %v", v.word, v.want, prim)
+		}
+	}
+}
+
+func TestWelshSpanishLl(t *testing.T) {
+	vals := []struct {
+		word, prim, sec string
+	}{
+		// welsh 'L'
+		{"llewellyn", "LLN", ""},
+		{"lloyd", "LT", ""},
+		{"llanelli", "LNL", ""},
+		{"llandudno", "LNTTN", ""},
+		{"llangollen", "LNKLN", ""},
+		// spanish 'Y' glide has an alternate without the 'L'
+		{"llama", "LM", "M"},
+		{"llano", "LN", "N"},
+		{"llanes", "LNS", "NS"},
+		{"cabrillo", "KPRL", "KPR"},
+		{"castillo", "KSTL", "KST"},
+	}
+
+	e := &Encoder{}
+	for _, v := range vals {
+		if prim, sec := e.Encode(v.word); prim != v.prim || sec != v.sec {
+			t.Errorf("Expected '%v' to be %v %v, got %v %v", v.word, v.prim, v.sec, prim, sec)
+		}
+	}
+}
+
+func TestHiatusEa(t *testing.T) {
+	table := []struct {
+		in, def, preserved string
+	}{
+		// two syllables
+		{"create", "KRAT", "KRAAT"},
+		{"react", "RAKT", "RAAKT"},
+		{"theater", "0ATAR", "0AATAR"},
+		{"idea", "ATA", "ATAA"},
+		{"real", "RAL", "RAAL"},
+		{"area", "ARA", "ARAA"},
+		{"preamble", "PRAMPAL", "PRAAMPAL"},
+		// one syllable
+		{"great", "KRAT", "KRAT"},
+		{"realm", "RALM", "RALM"},
+		{"creature", "KRAXAR", "KRAXAR"},
+	}
+
+	def := &Encoder{EncodeVowels: true}
+	preserved := &Encoder{EncodeVowels: true, PreserveVowelRuns: true}
+	for _, test := range table {
+		if prim, _ := def.Encode(test.in); prim != test.def {
+			t.Errorf("Expected '%v' to be %v, got %v", test.in, test.def, prim)
+		}
+		if prim, _ := preserved.Encode(test.in); prim != test.preserved {
+			t.Errorf("Expected '%v' to be %v with PreserveVowelRuns, got %v", test.in, test.preserved, prim)
+		}
+	}
+
+	// respelled with a 'Y' glide
+	for _, p := range [][2]string{{"create", "creyate"}, {"idea", "ideya"}} {
+		p1, _ := preserved.Encode(p[0])
+		p2, _ := preserved.Encode(p[1])
+		if p1 != p2 {
+			t.Errorf("Expected '%v' (%v) and '%v' (%v) to be equal with PreserveVowelRuns", p[0], p1, p[1], p2)
+		}
+	}
+}
+
+func TestFinalTz(t *testing.T) {
+	// the "ts" affricate matches "-TS"
+	testSoundsAlike(t, [][2]string{
+		{"katz", "cats"},
+		{"fritz", "frits"},
+		{"schmitz", "schmits"},
+		{"lutz", "luts"},
+		{"quartz", "quarts"},
+		{"spitz", "spits"},
+		{"hertz", "hurts"},
+		{"metz", "mets"},
+		{"waltz", "walts"},
+	})
+}
+
+func TestIsmIseIze(t *testing.T) {
+	// british "-ISE" matches american "-IZE"
+	testSoundsAlike(t, [][2]string{
+		{"realise", "realize"},
+		{"organise", "organize"},
+		{"criticise", "criticize"},
+		{"analyse", "analyze"},
+		{"apologise", "apologize"},
+		{"recognise", "recognize"},
+	})
+
+	e := &Encoder{}
+	for _, v := range []struct{ word, prim, sec string }{
+		{"baptism", "PPTSM", ""},
+		{"prism", "PRSM", ""},
+		{"schism", "SKSM", "SSM"},
+		{"chasm", "KSM", "XSM"},
+	} {
+		if prim, sec := e.Encode(v.word); prim != v.prim || sec != v.sec {
+			t.Errorf("Expected '%v' to be %v %v, got %v %v", v.word, v.prim, v.sec, prim, sec)
+		}
+	}
+}
+
+func TestGCompounds(t *testing.T) {
+	// a 'G' at the end of the first word of a compound doesn't form
+	// a digraph with the start of the second word
+	testSoundsAlike(t, [][2]string{
+		{"flagship", "flag ship"},
+		{"kingship", "king ship"},
+		{"doghouse", "dog house"},
+		{"bigheaded", "big headed"},
+		{"foghorn", "fog horn"},
+		{"jughandle", "jug handle"},
+		{"dogharbor", "dog harbor"},
+		{"hogwash", "hog wash"},
+		{"bagged", "bagd"},
+	})
+}
+
+func TestThToT(t *testing.T) {
+	testSoundsAlike(t, [][2]string{
+		{"thomas", "tomas"},
+		{"thompson", "tompson"},
+		{"thames", "tems"},
+		{"esther", "ester"},
+		{"thailand", "tailand"},
+		{"theresa", "teresa"},
+		{"nathalie", "natalie"},
+		{"thierry", "tierry"},
+		{"thibault", "tibo"},
+		// british "antony"
+		{"anthony", "antony"},
+	})
+
+	// american "anthony" keeps the "TH" as the primary
+	e := &Encoder{}
+	if prim, sec := e.Encode("anthony"); prim != "AN0N" || sec != "ANTN" {
+		t.Errorf("Expected 'anthony' to be AN0N ANTN, got %v %v", prim, sec)
+	}
+}
+
+func TestDropInitialVowel(t *testing.T) {
+	vals := []struct {
+		word, def, dropped string
+	}{
+		{"omar", "AMR", "MR"},
+		{"apple", "APL", "PL"},
+		{"ahmed", "AMT", "HMT"},
+		{"ihsan", "ASN", "HSN"},
+		{"ohm", "AM", "M"},
+		{"ehrlich", "ARLK", "RLK"},
+		{"ellen", "ALN", "LN"},
+		// silent initial 'H' and 'W'
+		{"hour", "AR", "R"},
+		{"honest", "ANST", "NST"},
+		{"wright", "RT", "RT"},
+		// consonant first is unchanged
+		{"smith", "SM0", "SM0"},
+		{"eye", "A", ""},
+	}
+
+	e := &Encoder{}
+	d := &Encoder{DropInitialVowel: true}
+	for _, v := range vals {
+		if prim, _ := e.Encode(v.word); prim != v.def {
+			t.Errorf("Expected '%v' to be %v, got %v", v.word, v.def, prim)
+		}
+		if prim, _ := d.Encode(v.word); prim != v.dropped {
+			t.Errorf("Expected '%v' with DropInitialVowel to be %v, got %v", v.word, v.dropped, prim)
+		}
+	}
+
+	// only the initial vowel is dropped
+	dv := &Encoder{DropInitialVowel: true, EncodeVowels: true}
+	if prim, _ := dv.Encode("omar"); prim != "MAR" {
+		t.Errorf("Expected 'omar' with DropInitialVowel and EncodeVowels to be MAR, got %v", prim)
+	}
+}
+
+func TestFrenchOeur(t *testing.T) {
+	// the vowels collapse and the 'R' is kept like "-EUR"
+	testSoundsAlike(t, [][2]string{
+		{"coeur", "cur"},
+		{"lacoeur", "lacur"},
+		{"crevecoeur", "crevecur"},
+		{"rancoeur", "rancur"},
+		{"chauffeur", "shofur"},
+		{"flaneur", "flanur"},
+		{"douceur", "doosur"},
+		// american "amachur" with a french 'T' alternate
+		{"amateur", "amachur"},
+		{"amateur", "amatur"},
+	})
+}
+
+func TestGenerateTestData(t *testing.T) {
+	for _, name := range []string{"cc-metaphone3.test", "tz-metaphone3.test", "welsh-spanish-ll-metaphone3.test"} {
+		file := filepath.Join("testdata", name)
+		csvFile, err := os.Open(file)
+		if err != nil {
+			t.Fatal(err)
+		}
+		lines, err := csv.NewReader(csvFile).ReadAll()
+		csvFile.Close()
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		var words []string
+		for _, line := range lines {
+			words = append(words, line[0])
+		}
+
+		want, err := ioutil.ReadFile(file)
+		if err != nil {
+			t.Fatal(err)
+		}
+		buf := &bytes.Buffer{}
+		GenerateTestData(words, buf)
+		if got := buf.String(); got != string(want) {
+			t.Errorf("Expected GenerateTestData to match %v, got:\n%v", file, got)
+		}
+	}
+
+	buf := &bytes.Buffer{}
+	GenerateTestData(nil, buf)
+	if got := buf.String(); got != "" {
+		t.Errorf("Expected blank output for no words, got %q", got)
+	}
+}
+
+func TestCollapseRepeats(t *testing.T) {
+	pairs := [][2]string{
+		{"hahaha", "haha"},
+		{"hahahahaha", "ha"},
+		{"hehehe", "hehe"},
+		{"lol", "lolol"},
+		{"lol", "lololol"},
+		{"muahahaha", "muahaha"},
+		{"bwahahaha", "bwahaha"},
+		{"blahblahblah", "blahblah"},
+		{"xoxoxo", "xoxo"},
+	}
+
+	// the extra repeats are dropped so both encode identically
+	for _, e := range allEncoders() {
+		e.CollapseRepeats = true
+		for _, p := range pairs {
+			p1, s1 := e.Encode(p[0])
+			p2, s2 := e.Encode(p[1])
+			if p1 != p2 || s1 != s2 {
+				t.Errorf("Expected '%v' (%v, %v) and '%v' (%v, %v) to be equal with vowels=%v exact=%v",
+					p[0], p1, s1, p[1], p2, s2, e.EncodeVowels, e.EncodeExact)
+			}
+		}
+	}
+
+	// without the option the repeats are encoded
+	e := &Encoder{}
+	if e.SameSound("lol", "lololol") {
+		t.Errorf("Expected 'lol' and 'lololol' to differ without CollapseRepeats")
+	}
+}
+
+func TestGeousGious(t *testing.T) {
+	pairs := [][2]string{
+		{"gorgeous", "gorjus"},
+		{"outrageous", "outrajus"},
+		{"courageous", "curajus"},
+		{"religious", "relijus"},
+		{"prodigious", "prodijus"},
+		{"contagious", "contajus"},
+		{"egregious", "egrejus"},
+	}
+
+	// the soft G has no hard alternate, so both encode identically
+	for _, e := range allEncoders() {
+		for _, p := range pairs {
+			p1, s1 := e.Encode(p[0])
+			p2, s2 := e.Encode(p[1])
+			if p1 != p2 || s1 != s2 {
+				t.Errorf("Expected '%v' (%v, %v) and '%v' (%v, %v) to be equal with vowels=%v exact=%v",
+					p[0], p1, s1, p[1], p2, s2, e.EncodeVowels, e.EncodeExact)
+			}
+		}
+	}
+}
+
+func TestLeTransposition(t *testing.T) {
+	// a syllabic "-LE" after a consonant is a schwa before the 'L', so the
+	// vowel is moved in front of it, otherwise the 'L' keeps its vowel
+	e := &Encoder{EncodeVowels: true}
+	vals := []struct {
+		word, want string
+	}{
+		// transposed
+		{"triangle", "TRANKAL"},
+		{"example", "AKSAMPAL"},
+		{"people", "PAPAL"},
+		{"little", "LATAL"},
+		{"uncle", "ANKAL"},
+		{"cycles", "SAKALS"},
+		{"angled", "ANKALT"},
+		{"tablespoon", "TAPALSPA"},
+		{"gentleman", "JANTALMA"},
+		{"settlement", "SATALMAN"},
+		{"entitlement", "ANTATALM"},
+		{"battlement", "PATALMAN"},
+		{"miracles", "MARAKALS"},
+		{"obstacles", "APSTAKAL"},
+		// not transposed
+		{"sophocles", "SAFAKLAS"},
+		{"pericles", "PARAKLAS"},
+		{"supplement", "SAPLAMAN"},
+		{"complement", "KAMPLAMA"},
+		{"problem", "PRAPLAM"},
+		{"emblem", "AMPLAM"},
+		{"splendid", "SPLANTAT"},
+		{"goblet", "KAPLAT"},
+		{"athlete", "A0LAT"},
+		{"needless", "NATLAS"},
+		{"stanley", "STANLA"},
+	}
+
+	for _, v := range vals {
+		if prim, _ := e.Encode(v.word); prim != v.want {
+			t.Errorf("Expected '%v' to be %v, got %v", v.word, v.want, prim)
+		}
+	}
+}
+
+func TestTionClusters(t *testing.T) {
+	// the "-TION" is always "XN", the cluster before it keeps its own sound
+	e := &Encoder{}
+	vals := []struct {
+		word, want string
+	}{
+		// "-STION"
+		{"question", "KSXN"},
+		{"suggestion", "SKJSXN"},
+		{"combustion", "KMPSXN"},
+		{"digestion", "TJSXN"},
+		{"exhaustion", "AKSSXN"},
+		// "-NTION"
+		{"mention", "MNXN"},
+		{"attention", "ATNXN"},
+		{"intention", "ANTNXN"},
+		// "-CTION"
+		{"action", "AKXN"},
+		{"fraction", "FRKXN"},
+		{"fiction", "FKXN"},
+		{"function", "FNKXN"},
+		{"distinction", "TSTNKXN"},
+		// vowel before
+		{"nation", "NXN"},
+		{"station", "STXN"},
+		{"partition", "PRTXN"},
+	}
+
+	for _, v := range vals {
+		if prim, _ := e.Encode(v.word); prim != v.want {
+			t.Errorf("Expected '%v' to be %v, got %v", v.word, v.want, prim)
+		}
+	}
+}
+
+func TestPhAsV(t *testing.T) {
+	testSoundsAlike(t, [][2]string{
+		{"stephen", "steven"},
+		{"stephens", "stevens"},
+		{"stephenson", "stevenson"},
+		{"nephew", "nevew"},
+		{"nephews", "nevews"},
+	})
+}
+
+func TestLigatureOe(t *testing.T) {
+	pairs := [][2]string{
+		{"œuvre", "oeuvre"},
+		{"cœur", "coeur"},
+		{"Œdipus", "Oedipus"},
+		{"manœuvre", "manoeuvre"},
+		{"fœtus", "foetus"},
+		{"sœur", "soeur"},
+	}
+
+	// the ligature is expanded so both encode identically
+	for _, e := range allEncoders() {
+		for _, p := range pairs {
+			p1, s1 := e.Encode(p[0])
+			p2, s2 := e.Encode(p[1])
+			if p1 != p2 || s1 != s2 {
+				t.Errorf("Expected '%v' (%v, %v) and '%v' (%v, %v) to be equal with vowels=%v exact=%v",
+					p[0], p1, s1, p[1], p2, s2, e.EncodeVowels, e.EncodeExact)
+			}
+		}
+	}
+}
+
+func TestEncodeWithLen(t *testing.T) {
+	vals := []struct {
+		in      string
+		runeLen int
+	}{
+		{"Lee", 3},
+		{"Leonardo", 8},
+		{"Muñoz", 5},
+		{"Gößmann", 7},
+		{"John's", 6},
+		{"", 0},
+	}
+
+	for _, e := range allEncoders() {
+		for _, v := range vals {
+			p1, s1, runeLen := e.EncodeWithLen(v.in)
+			p2, s2 := e.Encode(v.in)
+			if p1 != p2 || s1 != s2 {
+				t.Errorf("Expected '%v' to be %v %v, got %v %v", v.in, p2, s2, p1, s1)
+			}
+			if runeLen != v.runeLen {
+				t.Errorf("Expected '%v' to have length %v, got %v", v.in, v.runeLen, runeLen)
+			}
+		}
+	}
+}
+
+func TestPortugueseAo(t *testing.T) {
+	// the nasal "ÃO" is a single vowel without an 'N', and 'Ç' is 'S'
+	e := &Encoder{EncodeVowels: true}
+	vals := []struct {
+		word, want string
+	}{
+		{"São", "SA"},
+		{"João", "JA"},
+		{"Conceição", "KANSASA"},
+		{"Assunção", "ASANSA"},
+		{"Falcão", "FALKA"},
+		{"Brandão", "PRANTA"},
+		{"Simões", "SAMAS"},
+	}
+
+	for _, v := range vals {
+		if prim, _ := e.Encode(v.word); prim != v.want {
+			t.Errorf("Expected '%v' to be %v, got %v", v.word, v.want, prim)
+		}
+	}
+
+	// the tilde can be left off
+	testSoundsAlike(t, [][2]string{
+		{"São", "Sao"},
+		{"João", "Joao"},
+		{"Falcão", "Falcao"},
+		{"Brandão", "Brandao"},
+		{"Romão", "Romao"},
+	})
+}
+
+func TestMatchesAny(t *testing.T) {
+	e := &Encoder{}
+	keys := map[string]struct{}{"XMT": {}, "JNS": {}, "": {}}
+
+	vals := []struct {
+		in   string
+		want bool
+	}{
+		{"Schmidt", true}, // primary XMT
+		{"Smith", true},   // secondary XMT
+		{"Jones", true},
+		{"Johnson", false},
+		{"", false},
+		{"'", false},
+	}
+
+	for _, v := range vals {
+		if got := e.MatchesAny(v.in, keys); got != v.want {
+			t.Errorf("MatchesAny('%v') wanted %v, got %v", v.in, v.want, got)
+		}
+	}
+
+	if e.MatchesAny("Smith", nil) {
+		t.Errorf("Expected nothing to match a nil key set")
+	}
+}
+
+func TestAdverbEndings(t *testing.T) {
+	// "-LY" is "LA" after a silent 'E', and "-ALLY", "-ILY" keep their vowel
+	// except for the unspoken 'A' in "-ICALLY"
+	e := &Encoder{EncodeVowels: true}
+	vals := []struct {
+		word, want string
+	}{
+		{"really", "RALA"},
+		{"totally", "TATALA"},
+		{"finally", "FANALA"},
+		{"nicely", "NASLA"},
+		{"lovely", "LAFLA"},
+		{"safely", "SAFLA"},
+		{"happily", "HAPALA"},
+		{"easily", "ASALA"},
+		{"heavily", "HAFALA"},
+		{"basically", "PASAKLA"},
+		{"typically", "TAPAKLA"},
+		{"musically", "MASAKLA"},
+	}
+
+	for _, v := range vals {
+		if prim, _ := e.Encode(v.word); prim != v.want {
+			t.Errorf("Expected '%v' to be %v, got %v", v.word, v.want, prim)
+		}
+	}
+
+	testSoundsAlike(t, [][2]string{
+		{"really", "reely"},
+		{"happily", "hapily"},
+		{"basically", "basicly"},
+		{"musically", "musicly"},
+	})
+}
+
+func TestFrenchQu(t *testing.T) {
+	pairs := [][2]string{
+		{"quiche", "keesh"},
+		{"bouquet", "bookay"},
+		{"croquet", "crokay"},
+		{"mosque", "mosk"},
+		{"quay", "key"},
+		{"marquee", "markee"},
+		{"baroque", "barok"},
+		{"liqueur", "likur"},
+	}
+
+	// the "QU" is a single 'K' and the following silent vowel isn't encoded
+	for _, e := range allEncoders() {
+		for _, p := range pairs {
+			p1, s1 := e.Encode(p[0])
+			p2, s2 := e.Encode(p[1])
+			if p1 != p2 || s1 != s2 {
+				t.Errorf("Expected '%v' (%v, %v) and '%v' (%v, %v) to be equal with vowels=%v exact=%v",
+					p[0], p1, s1, p[1], p2, s2, e.EncodeVowels, e.EncodeExact)
+			}
+		}
+	}
+}
+
+func TestHardGFrontVowel(t *testing.T) {
+	// hard 'G', no 'J' alternate
+	for _, e := range allEncoders() {
+		for _, p := range [][2]string{
+			{"get", "jet"},
+			{"gift", "jift"},
+			{"gear", "jeer"},
+			{"girl", "jurl"},
+			{"beginning", "bejinning"},
+			{"forgive", "forjive"},
+		} {
+			if e.SameSound(p[0], p[1]) {
+				t.Errorf("Expected '%v' and '%v' to sound different with vowels=%v exact=%v",
+					p[0], p[1], e.EncodeVowels, e.EncodeExact)
+			}
+		}
+	}
+
+	// soft 'G', or names that keep the 'J' alternate
+	testSoundsAlike(t, [][2]string{
+		{"Gearldine", "Jearldine"},
+		{"Forget", "Forjet"},
+		{"gem", "jem"},
+		{"gin", "jin"},
+		{"gym", "jim"},
+		{"giraffe", "jiraffe"},
+	})
+}
+
+func TestHebrewPh(t *testing.T) {
+	testSoundsAlike(t, [][2]string{
+		{"Raphael", "Rafael"},
+		{"Joseph", "Josef"},
+		{"Ephraim", "Efraim"},
+		{"Zephaniah", "Zefaniah"},
+		{"Sephardic", "Sefardic"},
+		{"Naphtali", "Naftali"},
+		{"Pharaoh", "Farao"},
+		// american 'F', the british 'V' is only an alternate with EncodeExact
+		{"nephew", "nefew"},
+	})
+}
+
+func TestEncodePhrase(t *testing.T) {
+	vals := []struct {
+		in    string
+		words []string
+	}{
+		{"San Francisco", []string{"San", "Francisco"}},
+		{"  New\tYork ", []string{"New", "York"}},
+		{"Mary Jo Smith", []string{"Mary", "Jo", "Smith"}},
+		{"Smith", []string{"Smith"}},
+		{" ", nil},
+		{"", nil},
+	}
+
+	for _, e := range allEncoders() {
+		for _, v := range vals {
+			results := e.EncodePhrase(v.in)
+			if len(results) != len(v.words) {
+				t.Errorf("Expected '%v' to have %v words, got %v", v.in, len(v.words), len(results))
+				continue
+			}
+			for i, r := range results {
+				prim, second := e.Encode(v.words[i])
+				if r.Word != v.words[i] || r.Primary != prim || r.Secondary != second {
+					t.Errorf("Expected '%v' word %v to be %v %v %v, got %v %v %v",
+						v.in, i, v.words[i], prim, second, r.Word, r.Primary, r.Secondary)
+				}
+			}
+		}
+	}
+
+	// suffixes are stripped from the whole phrase, not each word
+	e := &Encoder{StripNameSuffixes: true}
+	for _, in := range []string{"John Smith Jr", "John Smith, Jr.", "john smith iii"} {
+		results := e.EncodePhrase(in)
+		if len(results) != 2 || results[0].Primary != "JN" || results[1].Primary != "SM0" {
+			t.Errorf("Expected '%v' to be JN SM0 with StripNameSuffixes, got %v", in, results)
+		}
+	}
+	if results := (&Encoder{}).EncodePhrase("John Smith Jr"); len(results) != 3 {
+		t.Errorf("Expected 'John Smith Jr' to have 3 words without StripNameSuffixes, got %v", results)
+	}
+}